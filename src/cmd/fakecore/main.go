@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/fakecore"
+	"github.com/joho/godotenv"
+)
+
+// In-memory stand-in for the Core DataService, to run G2 without Core.
+// It listens on CORE_GRPC_ADDRESS and checks CORE_GRPC_TOKEN like Core. The data is
+// lost when it stops.
+//
+//	fakecore [-addr :50051] [-token secret]
+func main() {
+	_ = godotenv.Load()
+	addr := os.Getenv("CORE_GRPC_ADDRESS")
+	if addr == "" {
+		addr = ":50051"
+	}
+	var (
+		listen = flag.String("addr", addr, "listen address")
+		token  = flag.String("token", os.Getenv("CORE_GRPC_TOKEN"), "accepted token (default any)")
+	)
+	flag.Parse()
+	log.SetFlags(0)
+
+	srv, err := fakecore.Start(*listen, *token)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("fake Core listening on", srv.Addr())
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	srv.Stop()
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/handlers"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/provablyfair"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/web"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/ws"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/configs"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/grpcclient"
@@ -48,14 +55,73 @@ func main() {
 	// HTTP
 	http.HandleFunc("/web", withAPIVersion(web.HandleHTTP))
 
+	// Crash point profiles
+	profilesPath := os.Getenv("CRASH_PROFILES")
+	if profilesPath == "" {
+		profilesPath = "configs/crash_profiles.json"
+	}
+	if err := provablyfair.LoadProfiles(profilesPath); err != nil {
+		log.Println("⚠️ [main] Crash profiles not loaded, using built-in:", err)
+	}
+
+	// Server seed source
+	handlers.InitSeedSource()
+
+	// Crash rooms
+	roomsPath := os.Getenv("CRASH_ROOMS")
+	if roomsPath == "" {
+		roomsPath = "configs/crash_rooms.json"
+	}
+	if err := handlers.LoadRooms(roomsPath); err != nil {
+		log.Println("⚠️ [main] Crash rooms not loaded, using the single default room:", err)
+	}
+
+	// UM transactions
+	handlers.StartOutbox()
+
 	// Sync DB
-	go handlers.NextGame(0)
+	handlers.StartRooms()
+
+	// Ledger reconciliation
+	handlers.StartReconciler()
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	log.Println("Web server running on port", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	srv := &http.Server{Addr: ":" + port}
+	go func() {
+		log.Println("Web server running on port", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// Graceful shutdown: finish the live rounds, then stop serving
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	log.Println("🛑 [main] Signal", <-sig, "draining rooms")
+	go func() {
+		log.Println("🛑 [main] Signal", <-sig, "again, exiting without draining")
+		os.Exit(1)
+	}()
+	handlers.DrainRooms()
+	handlers.StopOutbox()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout())
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println("🛑 [main] HTTP shutdown:", err)
+	}
+	log.Println("🛑 [main] Stopped")
+}
+
+// shutdownTimeout reads SHUTDOWN_TIMEOUT_MS, the deadline for open HTTP requests once rooms are drained
+func shutdownTimeout() time.Duration {
+	ms, err := strconv.Atoi(os.Getenv("SHUTDOWN_TIMEOUT_MS"))
+	if err != nil || ms < 1 {
+		return 10 * time.Second
+	}
+	return time.Duration(ms) * time.Millisecond
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/grpcclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/handlers"
+	"github.com/joho/godotenv"
+)
+
+// Ledger reconciliation between the bets tables and the transactions sent to UM.
+// It reads CORE_GRPC_ADDRESS and CORE_GRPC_TOKEN like the server. Times are UTC.
+//
+//	reconcile -from 2025-05-01 [-to 2025-06-01] [-room main] [-correct] [-json]
+//
+// The exit status is 1 when there are findings.
+func main() {
+	var (
+		fromText  = flag.String("from", "", "window start, YYYY-MM-DD or RFC3339 (default 24h before -to)")
+		toText    = flag.String("to", "", "window end, YYYY-MM-DD or RFC3339 (default now)")
+		room      = flag.String("room", "", "room ID (default all rooms)")
+		roomsPath = flag.String("rooms", "configs/crash_rooms.json", "crash rooms config file")
+		correct   = flag.Bool("correct", false, "queue corrective transactions in the outbox")
+		asJSON    = flag.Bool("json", false, "print the report as JSON")
+	)
+	flag.Parse()
+	log.SetFlags(0)
+	_ = godotenv.Load()
+
+	to := time.Now().UTC()
+	if *toText != "" {
+		t, err := parseTime(*toText)
+		if err != nil {
+			log.Fatalln("-to:", err)
+		}
+		to = t
+	}
+	from := to.Add(-24 * time.Hour)
+	if *fromText != "" {
+		t, err := parseTime(*fromText)
+		if err != nil {
+			log.Fatalln("-from:", err)
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		log.Fatalln("-from must be before -to")
+	}
+
+	if p := os.Getenv("CRASH_ROOMS"); p != "" && !isFlagSet("rooms") {
+		*roomsPath = p
+	}
+	if err := handlers.LoadRooms(*roomsPath); err != nil {
+		log.Println("rooms not loaded, using the single default room:", err)
+	}
+	grpcclient.Connect(os.Getenv("CORE_GRPC_ADDRESS"))
+
+	report, err := handlers.Reconcile(from, to, *room, *correct)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatalln(err)
+		}
+	} else {
+		printReport(report)
+	}
+	if len(report.Findings) > 0 {
+		os.Exit(1)
+	}
+}
+
+// parseTime reads a date or an RFC3339 time
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func printReport(report handlers.ReconReport) {
+	fmt.Printf("window     %s - %s\n", report.From.Format(time.RFC3339), report.To.Format(time.RFC3339))
+	fmt.Printf("bets       %d\n", report.Bets)
+	fmt.Printf("in flight  %d\n", report.InFlight)
+	fmt.Printf("unknown    %d\n", report.Unknown)
+	fmt.Printf("pre-outbox %d\n", report.PreOutbox)
+	fmt.Printf("findings   %d\n", len(report.Findings))
+	fmt.Printf("corrected  %d\n", report.Corrected)
+	if len(report.Findings) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Printf("%-9s  %-8s  %8s  %8s  %8s  %-11s  %10s  %10s  %s\n",
+		"kind", "room", "game", "bet", "user", "ledger", "expected", "sent", "queued")
+	for _, f := range report.Findings {
+		fmt.Printf("%-9s  %-8s  %8d  %8d  %8d  %-11s  %10.2f  %10.2f  %v\n",
+			f.Kind, f.Room, f.GameID, f.BetID, f.UserID, f.Ledger, f.Expected, f.Sent, f.Queued)
+	}
+}
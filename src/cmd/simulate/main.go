@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/provablyfair"
+)
+
+// Monte Carlo simulator for crash profiles.
+//
+//	simulate [-profile weighted] [-version 0] [-n 1000000] [-target 2] [-mix 1.5:0.5,2:0.3,10:0.2]
+func main() {
+	var (
+		profileName  = flag.String("profile", "", "crash profile name (empty = default profile)")
+		version      = flag.Int("version", 0, "crash profile version (0 = latest)")
+		profilesPath = flag.String("profiles", "configs/crash_profiles.json", "crash profiles config file")
+		rounds       = flag.Int("n", 1000000, "number of simulated rounds")
+		target       = flag.Float64("target", 2, "auto-cashout target for the RTP report")
+		mix          = flag.String("mix", "1.5:0.5,2:0.3,10:0.2", "player strategies as target:stakeShare pairs")
+		workers      = flag.Int("workers", runtime.NumCPU(), "parallel workers")
+	)
+	flag.Parse()
+	log.SetFlags(0)
+
+	if err := provablyfair.LoadProfiles(*profilesPath); err != nil {
+		log.Println("profiles not loaded, only the built-in legacy profile is available:", err)
+	}
+	profile := provablyfair.DefaultProfile()
+	if *profileName != "" {
+		var ok bool
+		if profile, ok = provablyfair.GetProfile(*profileName, *version); !ok {
+			log.Fatalf("profile %s v%d not found", *profileName, *version)
+		}
+	}
+	strategies, err := parseMix(*mix)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if *rounds < 1 || *workers < 1 {
+		log.Fatalln("n and workers must be positive")
+	}
+
+	crashes := simulate(profile, *rounds, *workers)
+	sort.Float64s(crashes)
+
+	fmt.Printf("profile %s v%d (%s), %d rounds\n\n", profile.Name, profile.Version, profile.Kind, len(crashes))
+	printHistogram(crashes)
+	printSurvival(profile, crashes)
+	printRTP(profile, crashes, *target, strategies)
+}
+
+// simulate runs rounds with fresh random server seeds, exactly like production rounds
+func simulate(profile provablyfair.Profile, rounds, workers int) []float64 {
+	crashes := make([]float64, rounds)
+	var wg sync.WaitGroup
+	chunk := (rounds + workers - 1) / workers
+	for w := 0; w < workers; w++ {
+		from, to := w*chunk, min((w+1)*chunk, rounds)
+		if from >= to {
+			break
+		}
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			seed := make([]byte, 32)
+			for i := from; i < to; i++ {
+				if _, err := rand.Read(seed); err != nil {
+					panic(err)
+				}
+				crashes[i] = provablyfair.VerifyRound(profile, hex.EncodeToString(seed), "", int64(i+1)).Multiplier
+			}
+		}(from, to)
+	}
+	wg.Wait()
+	return crashes
+}
+
+// survivalAt returns the empirical P(crash >= x), crashes must be sorted
+func survivalAt(crashes []float64, x float64) float64 {
+	i := sort.SearchFloat64s(crashes, x-1e-9)
+	return float64(len(crashes)-i) / float64(len(crashes))
+}
+
+func printHistogram(crashes []float64) {
+	edges := []float64{1, 1.01, 1.5, 2, 3, 5, 10, 20, 50, 100, 1000, math.Inf(1)}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "crash range\tcount\tshare\t")
+	below := 0
+	for i := 0; i < len(edges)-1; i++ {
+		upto := sort.SearchFloat64s(crashes, edges[i+1]-1e-9)
+		from := sort.SearchFloat64s(crashes, edges[i]-1e-9)
+		if i == 0 {
+			below = from
+		}
+		n := upto - from
+		fmt.Fprintf(tw, "[%g, %g)\t%d\t%.4f%%\t\n", edges[i], edges[i+1], n, 100*float64(n)/float64(len(crashes)))
+	}
+	if below > 0 {
+		fmt.Fprintf(tw, "< 1\t%d\t%.4f%%\t\n", below, 100*float64(below)/float64(len(crashes)))
+	}
+	tw.Flush()
+	fmt.Println()
+}
+
+func printSurvival(profile provablyfair.Profile, crashes []float64) {
+	points := []float64{1.01, 1.1, 1.5, 2, 3, 5, 10, 20, 50, 100}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "x\tP(crash >= x) theory\tempirical\t")
+	for _, x := range points {
+		fmt.Fprintf(tw, "%.2f\t%.6f\t%.6f\t\n", x, profile.Survival(x), survivalAt(crashes, x))
+	}
+	tw.Flush()
+	fmt.Println()
+}
+
+func printRTP(profile provablyfair.Profile, crashes []float64, target float64, strategies []strategy) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "auto-cashout\tstake share\tRTP theory\tempirical\t")
+	fmt.Fprintf(tw, "%.2f\t-\t%.4f%%\t%.4f%%\t\n", target, 100*profile.TheoreticalRTP(target), 100*target*survivalAt(crashes, target))
+
+	var rtpTheory, rtpEmpirical float64
+	for _, s := range strategies {
+		theory := profile.TheoreticalRTP(s.target)
+		empirical := s.target * survivalAt(crashes, s.target)
+		rtpTheory += s.share * theory
+		rtpEmpirical += s.share * empirical
+		fmt.Fprintf(tw, "%.2f\t%.2f\t%.4f%%\t%.4f%%\t\n", s.target, s.share, 100*theory, 100*empirical)
+	}
+	tw.Flush()
+	fmt.Printf("\nhouse edge for the strategy mix: theory %.4f%%, empirical %.4f%%\n", 100*(1-rtpTheory), 100*(1-rtpEmpirical))
+}
+
+type strategy struct {
+	target float64
+	share  float64
+}
+
+// parseMix reads "target:share" pairs, shares are normalized to sum to 1
+func parseMix(mix string) ([]strategy, error) {
+	var list []strategy
+	total := 0.0
+	for _, part := range strings.Split(mix, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.SplitN(part, ":", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid strategy %q, expected target:share", part)
+		}
+		t, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || t < 1 {
+			return nil, fmt.Errorf("invalid target in %q", part)
+		}
+		s, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || s <= 0 {
+			return nil, fmt.Errorf("invalid share in %q", part)
+		}
+		list = append(list, strategy{target: t, share: s})
+		total += s
+	}
+	for i := range list {
+		list[i].share /= total
+	}
+	return list, nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/provablyfair"
+)
+
+// Offline verifier for auditors, it needs neither Core gRPC nor UM.
+//
+//	verify -seed <serverSeed> [-client <clientSeed>] [-nonce <n>] [-profile <name>] [-version <v>] [-hash <serverSeedHash>]
+//	verify -file games.csv|games.json
+func main() {
+	var (
+		seed         = flag.String("seed", "", "revealed server seed")
+		seedHash     = flag.String("hash", "", "published server seed hash to check against")
+		clientSeed   = flag.String("client", "", "round client seed")
+		nonce        = flag.Int64("nonce", 0, "round nonce")
+		profileName  = flag.String("profile", "", "crash profile name (empty = legacy weighted table)")
+		version      = flag.Int("version", 0, "crash profile version (0 = latest)")
+		profilesPath = flag.String("profiles", "configs/crash_profiles.json", "crash profiles config file")
+		file         = flag.String("file", "", "CSV or JSON export of games to check")
+	)
+	flag.Parse()
+	log.SetFlags(0)
+
+	if err := provablyfair.LoadProfiles(*profilesPath); err != nil {
+		log.Println("profiles not loaded, only the built-in legacy profile is available:", err)
+	}
+
+	if *file != "" {
+		games, err := readExport(*file)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if failed := checkGames(os.Stdout, games); failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *seed == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	profile, ok := provablyfair.GetProfile(*profileName, *version)
+	if !ok {
+		log.Fatalf("profile %s v%d not found", *profileName, *version)
+	}
+	v := provablyfair.VerifyRound(profile, *seed, *clientSeed, *nonce)
+
+	fmt.Printf("server seed      %s\n", v.ServerSeed)
+	fmt.Printf("server seed hash %s\n", v.ServerSeedHash)
+	if *seedHash != "" {
+		fmt.Printf("hash check       %s\n", okText(provablyfair.VerifyServerSeed(*seed, *seedHash)))
+	}
+	fmt.Printf("client seed      %s\n", v.ClientSeed)
+	fmt.Printf("nonce            %d\n", v.Nonce)
+	fmt.Printf("profile          %s v%d\n", v.Profile, v.ProfileVersion)
+	fmt.Printf("hmac             %s\n", v.HMAC)
+	fmt.Printf("ratio            %.12f\n", v.Ratio)
+	if v.Bucket != nil {
+		fmt.Printf("bucket           #%d %.2f-%.2f (weight %.2f)\n", v.BucketIndex, v.Bucket.Min, v.Bucket.Max, v.Bucket.Weight)
+		fmt.Printf("position ratio   %.12f\n", v.PositionRatio)
+	}
+	fmt.Printf("crash point      %.2f\n", v.Multiplier)
+}
+
+// checkGames verifies every game and writes one line per row to w, it returns the failed count.
+// Voided games never crashed, only their server seed is checked when it was revealed.
+func checkGames(w io.Writer, games []models.Game) int {
+	failed, voided := 0, 0
+	for _, g := range games {
+		if g.Status == models.GameVoided {
+			if g.ServerSeed == "" {
+				voided++
+				fmt.Fprintf(w, "%d\tVOIDED\tunverifiable, no server seed\n", g.ID)
+				continue
+			}
+			if problems := seedProblems(g); len(problems) > 0 {
+				failed++
+				fmt.Fprintf(w, "%d\tFAIL\tvoided, %s\n", g.ID, strings.Join(problems, "; "))
+				continue
+			}
+			voided++
+			fmt.Fprintf(w, "%d\tVOIDED\n", g.ID)
+			continue
+		}
+		profile, ok := provablyfair.GetProfile(g.Profile, g.ProfileVersion)
+		if !ok {
+			fmt.Fprintf(w, "%d\tFAIL\tprofile %s v%d not found\n", g.ID, g.Profile, g.ProfileVersion)
+			failed++
+			continue
+		}
+		v := provablyfair.VerifyRound(profile, g.ServerSeed, g.ClientSeed, g.Nonce)
+
+		problems := seedProblems(g)
+		if g.CrashAt != v.Multiplier {
+			problems = append(problems, fmt.Sprintf("crash %.2f, recomputed %.2f", g.CrashAt, v.Multiplier))
+		}
+
+		if len(problems) > 0 {
+			failed++
+			fmt.Fprintf(w, "%d\tFAIL\t%s\n", g.ID, strings.Join(problems, "; "))
+			continue
+		}
+		fmt.Fprintf(w, "%d\tOK\t%.2f\n", g.ID, v.Multiplier)
+	}
+	fmt.Fprintf(w, "checked %d games, %d failed, %d voided\n", len(games), failed, voided)
+	return failed
+}
+
+// seedProblems checks the revealed server seed against its hash and seed chain
+func seedProblems(g models.Game) []string {
+	var problems []string
+	if g.ServerSeedHash != "" && !provablyfair.VerifyServerSeed(g.ServerSeed, g.ServerSeedHash) {
+		problems = append(problems, "hash mismatch")
+	}
+	if g.SeedChainHash != "" {
+		position, ok := provablyfair.VerifyChainSeed(g.ServerSeed, g.SeedChainHash, g.SeedChainIndex)
+		switch {
+		case !ok:
+			problems = append(problems, "not in seed chain")
+		case position != g.SeedChainIndex:
+			problems = append(problems, fmt.Sprintf("seed chain position %d, stored %d", position, g.SeedChainIndex))
+		}
+	}
+	return problems
+}
+
+// readExport loads games from a JSON or CSV export.
+// Rows are either game objects or g2_games rows with the game JSON in a "game" column.
+func readExport(path string) ([]models.Game, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return readJSON(f)
+	}
+	return readCSV(f)
+}
+
+func readJSON(r io.Reader) ([]models.Game, error) {
+	var rows []map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("invalid JSON export: %w", err)
+	}
+	games := make([]models.Game, 0, len(rows))
+	for i, row := range rows {
+		fields := make(map[string]string, len(row))
+		for k, v := range row {
+			switch val := v.(type) {
+			case string:
+				fields[k] = val
+			case nil:
+			default:
+				b, _ := json.Marshal(val)
+				fields[k] = string(b)
+			}
+		}
+		g, err := gameFromFields(fields)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		games = append(games, g)
+	}
+	return games, nil
+}
+
+func readCSV(r io.Reader) ([]models.Game, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV export: %w", err)
+	}
+	if len(records) < 1 {
+		return nil, nil
+	}
+	header := records[0]
+	games := make([]models.Game, 0, len(records)-1)
+	for i, rec := range records[1:] {
+		fields := make(map[string]string, len(header))
+		for j, name := range header {
+			if j < len(rec) {
+				fields[name] = rec[j]
+			}
+		}
+		g, err := gameFromFields(fields)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+2, err)
+		}
+		games = append(games, g)
+	}
+	return games, nil
+}
+
+// gameFromFields maps loosely named columns (server_seed, serverSeed, ...) to a game
+func gameFromFields(fields map[string]string) (models.Game, error) {
+	var g models.Game
+	norm := make(map[string]string, len(fields))
+	for k, v := range fields {
+		norm[strings.ToLower(strings.ReplaceAll(k, "_", ""))] = strings.TrimSpace(v)
+	}
+
+	// Full game JSON column, as stored in g2_games.game
+	if raw := norm["game"]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &g); err != nil {
+			return g, fmt.Errorf("invalid game column: %w", err)
+		}
+	}
+
+	var err error
+	for key, v := range norm {
+		if v == "" {
+			continue
+		}
+		switch key {
+		case "id":
+			g.ID, err = strconv.ParseInt(v, 10, 64)
+		case "serverseed":
+			g.ServerSeed = v
+		case "serverseedhash":
+			g.ServerSeedHash = v
+		case "clientseed":
+			g.ClientSeed = v
+		case "nonce":
+			g.Nonce, err = strconv.ParseInt(v, 10, 64)
+		case "profile":
+			g.Profile = v
+		case "profileversion":
+			g.ProfileVersion, err = strconv.Atoi(v)
+		case "crashat":
+			g.CrashAt, err = strconv.ParseFloat(v, 64)
+		case "seedchainhash":
+			g.SeedChainHash = v
+		case "seedchainindex":
+			g.SeedChainIndex, err = strconv.Atoi(v)
+		case "status":
+			g.Status = v
+		}
+		if err != nil {
+			return g, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	// A voided game may never have revealed its seed, checkGames reports it as unverifiable
+	if g.ServerSeed == "" && g.Status != models.GameVoided {
+		return g, fmt.Errorf("missing server seed")
+	}
+	return g, nil
+}
+
+func okText(ok bool) string {
+	if ok {
+		return "OK"
+	}
+	return "MISMATCH"
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/provablyfair"
+)
+
+const testSeed = "4f9d3c0e2a8b7f6e5d4c3b2a1908f7e6d5c4b3a29180f7e6d5c4b3a291807f6e"
+
+// crashOf is the crash point of a round on the legacy profile
+func crashOf(seed, clientSeed string, nonce int64) float64 {
+	return provablyfair.VerifyRound(provablyfair.LegacyProfile, seed, clientSeed, nonce).Multiplier
+}
+
+func TestGameFromFields(t *testing.T) {
+	hash := provablyfair.HashServerSeed(testSeed)
+	for _, tc := range []struct {
+		name   string
+		fields map[string]string
+		want   models.Game
+		err    string
+	}{
+		{
+			name:   "snake case columns",
+			fields: map[string]string{"id": "7", "server_seed": testSeed, "server_seed_hash": hash, "client_seed": "c", "nonce": "7", "crash_at": "2.5", "profile": "weighted", "profile_version": "1"},
+			want:   models.Game{ID: 7, ServerSeed: testSeed, ServerSeedHash: hash, ClientSeed: "c", Nonce: 7, CrashAt: 2.5, Profile: "weighted", ProfileVersion: 1},
+		},
+		{
+			name:   "camel case columns and spaces",
+			fields: map[string]string{"ID": " 8 ", "serverSeed": testSeed, "seedChainHash": "terminal", "seedChainIndex": "3", "status": "finished"},
+			want:   models.Game{ID: 8, ServerSeed: testSeed, SeedChainHash: "terminal", SeedChainIndex: 3, Status: models.GameFinished},
+		},
+		{
+			name:   "game column with overriding columns",
+			fields: map[string]string{"id": "9", "game": `{"id":1,"serverSeed":"` + testSeed + `","crashAt":1.5,"nonce":9}`, "crash_at": "3"},
+			want:   models.Game{ID: 9, ServerSeed: testSeed, CrashAt: 3, Nonce: 9},
+		},
+		{
+			name:   "voided without a server seed",
+			fields: map[string]string{"id": "10", "status": "voided", "server_seed_hash": hash},
+			want:   models.Game{ID: 10, ServerSeedHash: hash, Status: models.GameVoided},
+		},
+		{name: "finished without a server seed", fields: map[string]string{"id": "11", "status": "finished"}, err: "missing server seed"},
+		{name: "bad id", fields: map[string]string{"id": "x", "server_seed": testSeed}, err: "invalid id"},
+		{name: "bad crash", fields: map[string]string{"crash_at": "high", "server_seed": testSeed}, err: "invalid crashat"},
+		{name: "bad game column", fields: map[string]string{"game": "{"}, err: "invalid game column"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := gameFromFields(tc.fields)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("error = %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g != tc.want {
+				t.Errorf("game = %+v, want %+v", g, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadExports(t *testing.T) {
+	csvExport := "id,server_seed,client_seed,nonce,crash_at,status\n" +
+		"1," + testSeed + ",c,1,2.5,finished\n" +
+		"2,,,,,voided\n"
+	jsonExport := `[
+		{"id": 1, "server_seed": "` + testSeed + `", "client_seed": "c", "nonce": 1, "crash_at": 2.5, "status": "finished"},
+		{"id": 2, "server_seed": null, "status": "voided"}
+	]`
+	want := []models.Game{
+		{ID: 1, ServerSeed: testSeed, ClientSeed: "c", Nonce: 1, CrashAt: 2.5, Status: models.GameFinished},
+		{ID: 2, Status: models.GameVoided},
+	}
+
+	for name, read := range map[string]func() ([]models.Game, error){
+		"csv":  func() ([]models.Game, error) { return readCSV(strings.NewReader(csvExport)) },
+		"json": func() ([]models.Game, error) { return readJSON(strings.NewReader(jsonExport)) },
+	} {
+		games, err := read()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(games) != len(want) {
+			t.Fatalf("%s: read %d games, want %d", name, len(games), len(want))
+		}
+		for i := range want {
+			if games[i] != want[i] {
+				t.Errorf("%s row %d = %+v, want %+v", name, i+1, games[i], want[i])
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		read func() ([]models.Game, error)
+		err  string
+	}{
+		{"csv row", func() ([]models.Game, error) { return readCSV(strings.NewReader("id,server_seed\n1,\n")) }, "row 2: missing server seed"},
+		{"csv quotes", func() ([]models.Game, error) { return readCSV(strings.NewReader("id\n\"1\n")) }, "invalid CSV export"},
+		{"json row", func() ([]models.Game, error) { return readJSON(strings.NewReader(`[{"id":"x"}]`)) }, "row 1: invalid id"},
+		{"json shape", func() ([]models.Game, error) { return readJSON(strings.NewReader(`{"id":1}`)) }, "invalid JSON export"},
+	} {
+		if _, err := tc.read(); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s error = %v, want %q", tc.name, err, tc.err)
+		}
+	}
+
+	if games, err := readExport(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Errorf("missing export read %d games", len(games))
+	}
+}
+
+func TestCheckGames(t *testing.T) {
+	hash := provablyfair.HashServerSeed(testSeed)
+	chain, err := provablyfair.NewSeedChain(3, filepath.Join(t.TempDir(), "chain.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, _, _ := chain.Next()
+	second, _, _, _ := chain.Next()
+
+	for _, tc := range []struct {
+		name string
+		game models.Game
+		line string
+		fail bool
+	}{
+		{
+			name: "ok",
+			game: models.Game{ID: 1, ServerSeed: testSeed, ServerSeedHash: hash, ClientSeed: "c", Nonce: 1, CrashAt: crashOf(testSeed, "c", 1)},
+			line: "1\tOK\t",
+		},
+		{
+			name: "legacy round",
+			game: models.Game{ID: 2, ServerSeed: testSeed, CrashAt: crashOf(testSeed, "", 0)},
+			line: "2\tOK\t",
+		},
+		{
+			name: "wrong crash",
+			game: models.Game{ID: 3, ServerSeed: testSeed, ServerSeedHash: hash, ClientSeed: "c", Nonce: 3, CrashAt: crashOf(testSeed, "c", 3) + 1},
+			line: "3\tFAIL\tcrash ",
+			fail: true,
+		},
+		{
+			name: "mismatched hash",
+			game: models.Game{ID: 4, ServerSeed: testSeed, ServerSeedHash: provablyfair.HashServerSeed("other"), Nonce: 4, ClientSeed: "c", CrashAt: crashOf(testSeed, "c", 4)},
+			line: "4\tFAIL\thash mismatch",
+			fail: true,
+		},
+		{
+			name: "unknown profile",
+			game: models.Game{ID: 5, ServerSeed: testSeed, Profile: "nope", ProfileVersion: 1},
+			line: "5\tFAIL\tprofile nope v1 not found",
+			fail: true,
+		},
+		{
+			name: "chain seed at its position",
+			game: models.Game{ID: 6, ServerSeed: second, SeedChainHash: chain.TerminalHash, SeedChainIndex: 2, Nonce: 6, CrashAt: crashOf(second, "", 6)},
+			line: "6\tOK\t",
+		},
+		{
+			name: "chain seed reused at a later position",
+			game: models.Game{ID: 7, ServerSeed: first, SeedChainHash: chain.TerminalHash, SeedChainIndex: 2, Nonce: 7, CrashAt: crashOf(first, "", 7)},
+			line: "7\tFAIL\tseed chain position 1, stored 2",
+			fail: true,
+		},
+		{
+			name: "voided",
+			game: models.Game{ID: 8, ServerSeed: testSeed, ServerSeedHash: hash, Status: models.GameVoided},
+			line: "8\tVOIDED\n",
+		},
+		{
+			name: "voided with a mismatched hash",
+			game: models.Game{ID: 9, ServerSeed: testSeed, ServerSeedHash: provablyfair.HashServerSeed("other"), Status: models.GameVoided},
+			line: "9\tFAIL\tvoided, hash mismatch",
+			fail: true,
+		},
+		{
+			name: "voided without a server seed",
+			game: models.Game{ID: 10, ServerSeedHash: hash, Status: models.GameVoided},
+			line: "10\tVOIDED\tunverifiable, no server seed",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out strings.Builder
+			failed := checkGames(&out, []models.Game{tc.game})
+			if (failed == 1) != tc.fail {
+				t.Errorf("failed = %d, want fail %v", failed, tc.fail)
+			}
+			if !strings.HasPrefix(out.String(), tc.line) {
+				t.Errorf("output %q, want a line starting %q", out.String(), tc.line)
+			}
+		})
+	}
+
+	var out strings.Builder
+	games := []models.Game{
+		{ID: 1, ServerSeed: testSeed, CrashAt: crashOf(testSeed, "", 0)},
+		{ID: 2, ServerSeed: testSeed, CrashAt: 0},
+		{ID: 3, Status: models.GameVoided},
+	}
+	if failed := checkGames(&out, games); failed != 1 {
+		t.Errorf("failed = %d, want 1", failed)
+	}
+	if !strings.HasSuffix(out.String(), "checked 3 games, 1 failed, 1 voided\n") {
+		t.Errorf("summary missing from %q", out.String())
+	}
+}
@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Defaults of the published multiplier curve
+const (
+	DefaultCurveRate = 0.00006
+	DefaultTick      = 100 * time.Millisecond
+)
+
+// Curve is the published growth of the multiplier, m(t) = e^(Rate·t) with t in
+// milliseconds since the round started, floored to two decimals.
+// It depends on time only, so the speed of a round tells nothing about its crash point.
+type Curve struct {
+	Rate float64       `json:"rate"`
+	Tick time.Duration `json:"-"`
+}
+
+// CurveFromEnv reads CRASH_CURVE_RATE and CRASH_TICK_MS with sane defaults
+func CurveFromEnv() Curve {
+	c := Curve{Rate: DefaultCurveRate, Tick: DefaultTick}
+	if r, err := strconv.ParseFloat(os.Getenv("CRASH_CURVE_RATE"), 64); err == nil && r > 0 {
+		c.Rate = r
+	}
+	if ms, err := strconv.Atoi(os.Getenv("CRASH_TICK_MS")); err == nil && ms > 0 {
+		c.Tick = time.Duration(ms) * time.Millisecond
+	}
+	return c
+}
+
+// MultiplierAt returns the multiplier after elapsed milliseconds
+func (c Curve) MultiplierAt(elapsedMs int64) float64 {
+	if elapsedMs <= 0 {
+		return 1
+	}
+	return math.Floor(math.Exp(c.Rate*float64(elapsedMs))*100) / 100
+}
@@ -0,0 +1,412 @@
+package engine
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+)
+
+// Round states
+const (
+	StateWaiting  = 0
+	StateRunning  = 1
+	StateCrashed  = 2
+	StateFinished = 4
+)
+
+var (
+	ErrGameStarted    = errors.New("game started")
+	ErrGameNotRunning = errors.New("game not running")
+	ErrBetNotFound    = errors.New("bet not found")
+	ErrBetCrashed     = errors.New("bet already crashed")
+	ErrBetPaid        = errors.New("bet already paid")
+	ErrBetSettling    = errors.New("bet is being settled")
+	ErrBetLimit       = errors.New("bet limit reached")
+	ErrGameMaxBet     = errors.New("game max bet reached")
+	ErrNoBets         = errors.New("no bets found")
+)
+
+// GameEngine owns the state of the live round.
+// Every read and write goes through its lock; callers only get copies.
+type GameEngine struct {
+	mu       sync.RWMutex
+	live     models.LiveGame
+	crashAt  float64
+	curve    Curve
+	start    time.Time
+	bets     map[int64][]models.Bet
+	index    cashoutIndex // unsettled bets by auto-cashout trigger
+	settling int          // claimed bets waiting for Settle
+	settled  *sync.Cond   // signalled when settling drops to 0
+}
+
+// New creates an engine with no round
+func New() *GameEngine {
+	e := &GameEngine{
+		live: models.LiveGame{GameState: StateFinished},
+		bets: make(map[int64][]models.Bet),
+	}
+	e.settled = sync.NewCond(&e.mu)
+	return e
+}
+
+// Open starts a new round waiting for bets and drops the previous round's bets
+func (e *GameEngine) Open(live models.LiveGame) models.LiveGame {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	live.GameState = StateWaiting
+	live.ServerTime = time.Now().UnixMilli()
+	e.live = live
+	e.crashAt = 0
+	e.start = time.Time{}
+	e.bets = make(map[int64][]models.Bet)
+	e.index = nil
+	e.settling = 0
+	return e.live
+}
+
+// checkBet validates a new bet against the round state and limits, lock must be held
+func (e *GameEngine) checkBet(userID int64, amount float64) error {
+	if e.live.GameState != StateWaiting {
+		return ErrGameStarted
+	}
+	limits := e.live.Limits
+
+	// Single Bet Amount
+	if amount > limits.MaxBet {
+		return ErrBetLimit
+	}
+
+	// User bets counts and amounts
+	userBets := e.bets[userID]
+	if len(userBets) >= limits.MaxUserBets {
+		return ErrBetLimit
+	}
+	var userTotal float64
+	for _, b := range userBets {
+		userTotal += b.Bet
+	}
+	if userTotal+amount > limits.MaxUserTotal {
+		return ErrBetLimit
+	}
+
+	// Game Max Bet Amount
+	var allTotal float64
+	for _, bets := range e.bets {
+		for _, b := range bets {
+			allTotal += b.Bet
+		}
+	}
+	if allTotal+amount > limits.MaxRoundTotal {
+		return ErrGameMaxBet
+	}
+	return nil
+}
+
+// CheckBet tells whether a bet would be accepted right now
+func (e *GameEngine) CheckBet(userID int64, amount float64) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.checkBet(userID, amount)
+}
+
+// PlaceBet adds a bet to the round, limits are checked again atomically
+func (e *GameEngine) PlaceBet(bet models.Bet) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.checkBet(bet.UserID, bet.Bet); err != nil {
+		return err
+	}
+	if bet.GameID != e.live.ID {
+		return ErrGameStarted
+	}
+	bet.State = models.BetOpen
+	e.bets[bet.UserID] = append(e.bets[bet.UserID], bet)
+	e.index.push(cashoutEntry{
+		trigger: cashoutTrigger(bet.Bet, bet.Multiplier, e.live.Limits.MaxWin),
+		betID:   bet.ID,
+		userID:  bet.UserID,
+	})
+	return nil
+}
+
+// CloseBetting moves the round to running and returns its bets ordered by ID.
+// No bet can be placed after this call.
+func (e *GameEngine) CloseBetting() []models.Bet {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.live.GameState = StateRunning
+	e.live.ServerTime = time.Now().UnixMilli()
+	return e.sortedBets()
+}
+
+// SetCrashPoint stores the crash point and the public client seed it was derived from
+func (e *GameEngine) SetCrashPoint(clientSeed string, crashAt float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.live.ClientSeed = clientSeed
+	e.crashAt = crashAt
+}
+
+// Start sets the curve start, from now on the multiplier is a function of time only
+func (e *GameEngine) Start(curve Curve, at time.Time) models.LiveGame {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.curve = curve
+	e.start = at
+	e.live.StartedAt = at.UnixMilli()
+	e.live.CurveRate = curve.Rate
+	e.live.Multiplier = 1
+	e.live.ServerTime = at.UnixMilli()
+	return e.live
+}
+
+// multiplierAt evaluates the curve, lock must be held
+func (e *GameEngine) multiplierAt(now time.Time) float64 {
+	return e.curve.MultiplierAt(now.Sub(e.start).Milliseconds())
+}
+
+// Tick evaluates the curve at now. It returns the bets due for auto-cashout, the
+// multiplier of this tick (never above the crash point) and whether the round crashed.
+// A tick can skip levels, due bets are paid at their own target, not at the tick multiplier.
+func (e *GameEngine) Tick(now time.Time) ([]models.Bet, float64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.live.GameState != StateRunning {
+		return nil, e.live.Multiplier, false
+	}
+	multiplier := math.Min(e.multiplierAt(now), e.crashAt)
+	e.live.Multiplier = multiplier
+	e.live.ServerTime = now.UnixMilli()
+
+	// Auto-cashout on target, or once the bet reached the max win. Due bets leave the
+	// index and move to settling, so each one is handed out once, in trigger order.
+	var due []models.Bet
+	for _, entry := range e.index.popDue(multiplier) {
+		bet := e.findBet(entry.userID, entry.betID)
+		if bet == nil || bet.State != models.BetOpen {
+			continue
+		}
+		e.claim(bet)
+		due = append(due, *bet)
+	}
+
+	crashed := false
+	if multiplier >= e.crashAt {
+		e.live.GameState = StateCrashed
+		crashed = true
+
+		// Whatever is still open lost with the crash
+		for _, bets := range e.bets {
+			for i := range bets {
+				if bets[i].State == models.BetOpen {
+					bets[i].State = models.BetLost
+				}
+			}
+		}
+	}
+	return due, multiplier, crashed
+}
+
+// claim moves an open bet to settling, lock must be held. A claimed bet keeps the
+// multiplier it earned until Settle, even once the round crashed.
+func (e *GameEngine) claim(bet *models.Bet) {
+	bet.State = models.BetSettling
+	e.settling++
+}
+
+// WaitSettled blocks until every claimed bet of the round is settled
+func (e *GameEngine) WaitSettled() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for e.settling > 0 {
+		e.settled.Wait()
+	}
+}
+
+// Finish marks the round as finished
+func (e *GameEngine) Finish() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.live.GameState = StateFinished
+}
+
+// current returns the multiplier at this instant, it fails once the crash point is reached
+// even if the loop did not tick yet. Lock must be held.
+func (e *GameEngine) current() (float64, error) {
+	if e.live.GameState != StateRunning || e.start.IsZero() {
+		return 0, ErrGameNotRunning
+	}
+	multiplier := e.multiplierAt(time.Now())
+	if multiplier >= e.crashAt {
+		return 0, ErrGameNotRunning
+	}
+	return multiplier, nil
+}
+
+// stateError maps a bet that is no longer open to its error
+func stateError(bet *models.Bet) error {
+	if bet.State == models.BetSettling || bet.State == models.BetUnpaid {
+		return ErrBetSettling
+	}
+	return ErrBetPaid
+}
+
+// Cashout claims a bet for a user cashout at the current multiplier.
+// The bet moves to settling, it must then be settled.
+func (e *GameEngine) Cashout(userID, betID int64) (models.Bet, float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	multiplier, err := e.current()
+	if err != nil {
+		return models.Bet{}, 0, err
+	}
+	bet := e.findBet(userID, betID)
+	if bet == nil {
+		return models.Bet{}, 0, ErrBetNotFound
+	}
+	if bet.State != models.BetOpen {
+		return models.Bet{}, 0, stateError(bet)
+	}
+	if bet.Multiplier <= multiplier {
+		return models.Bet{}, 0, ErrBetCrashed
+	}
+	e.claim(bet)
+	return *bet, multiplier, nil
+}
+
+// CashoutAll claims every open bet of the user that did not reach its target yet
+func (e *GameEngine) CashoutAll(userID int64) ([]models.Bet, float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	multiplier, err := e.current()
+	if err != nil {
+		return nil, 0, err
+	}
+	bets := e.bets[userID]
+	if len(bets) == 0 {
+		return nil, 0, ErrNoBets
+	}
+	var claimed []models.Bet
+	for i := range bets {
+		if bets[i].State != models.BetOpen || bets[i].Multiplier <= multiplier {
+			continue
+		}
+		e.claim(&bets[i])
+		claimed = append(claimed, bets[i])
+	}
+	return claimed, multiplier, nil
+}
+
+// Settle credits a settling bet, it returns false unless the bet was settling
+func (e *GameEngine) Settle(userID, betID int64, payout float64, by string, on float64) (models.Bet, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	bet := e.findBet(userID, betID)
+	if bet == nil || bet.State != models.BetSettling {
+		return models.Bet{}, false
+	}
+	bet.State = models.BetWon
+	bet.Payout = payout
+	bet.CheckoutBy = by
+	bet.CheckoutOn = on
+	e.settling--
+	if e.settling == 0 {
+		e.settled.Broadcast()
+	}
+	return *bet, true
+}
+
+// Abandon gives up on the credit of a settling bet: the bet is stored unpaid with the
+// payout it earned and no longer holds the round. It returns false unless the bet was settling.
+func (e *GameEngine) Abandon(userID, betID int64, payout float64, by string, on float64) (models.Bet, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	bet := e.findBet(userID, betID)
+	if bet == nil || bet.State != models.BetSettling {
+		return models.Bet{}, false
+	}
+	bet.State = models.BetUnpaid
+	bet.Payout = payout
+	bet.CheckoutBy = by
+	bet.CheckoutOn = on
+	e.settling--
+	if e.settling == 0 {
+		e.settled.Broadcast()
+	}
+	return *bet, true
+}
+
+// Live returns a copy of the live round
+func (e *GameEngine) Live() models.LiveGame {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.live
+}
+
+// Bets returns a copy of all live bets keyed by user
+func (e *GameEngine) Bets() map[int64][]models.Bet {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.copyBets()
+}
+
+// AllBets returns a copy of all live bets ordered by ID
+func (e *GameEngine) AllBets() []models.Bet {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.sortedBets()
+}
+
+// Snapshot returns consistent copies of the live round and its bets
+func (e *GameEngine) Snapshot() (models.LiveGame, map[int64][]models.Bet) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.live, e.copyBets()
+}
+
+// copyBets deep copies the bets map, lock must be held
+func (e *GameEngine) copyBets() map[int64][]models.Bet {
+	out := make(map[int64][]models.Bet, len(e.bets))
+	for userID, bets := range e.bets {
+		c := make([]models.Bet, len(bets))
+		copy(c, bets)
+		out[userID] = c
+	}
+	return out
+}
+
+// sortedBets returns all bets ordered by ID, lock must be held
+func (e *GameEngine) sortedBets() []models.Bet {
+	var all []models.Bet
+	for _, bets := range e.bets {
+		all = append(all, bets...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all
+}
+
+// findBet returns a pointer into the bets map, lock must be held
+func (e *GameEngine) findBet(userID, betID int64) *models.Bet {
+	bets := e.bets[userID]
+	for i := range bets {
+		if bets[i].ID == betID {
+			return &bets[i]
+		}
+	}
+	return nil
+}
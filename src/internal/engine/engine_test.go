@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+)
+
+// settleLog counts the successful Settle calls of every bet
+type settleLog struct {
+	mu     sync.Mutex
+	counts map[int64]int
+}
+
+func (l *settleLog) settle(t *testing.T, e *GameEngine, bet models.Bet, by string) {
+	settled, ok := e.Settle(bet.UserID, bet.ID, bet.Bet*2, by, 2)
+	if !ok {
+		return
+	}
+	if settled.State != models.BetWon {
+		t.Errorf("bet %d settled as %s", bet.ID, settled.State)
+	}
+	l.mu.Lock()
+	l.counts[bet.ID]++
+	l.mu.Unlock()
+}
+
+func TestConcurrentCashoutsSettleOnce(t *testing.T) {
+	const bets, users = 2000, 40
+	e, _ := seedRound(t, bets, users, 1.01, 3)
+	// A fast curve on the wall clock, user cashouts read time.Now()
+	e.Start(Curve{Rate: 0.005, Tick: time.Millisecond}, time.Now())
+
+	settles := &settleLog{counts: map[int64]int{}}
+	claims := make(chan models.Bet, bets)
+	var settlers, players sync.WaitGroup
+
+	// Settlers race each other on every claim, like a retried credit
+	for i := 0; i < 4; i++ {
+		settlers.Add(1)
+		go func() {
+			defer settlers.Done()
+			for bet := range claims {
+				settles.settle(t, e, bet, "Multiplier")
+				settles.settle(t, e, bet, "Multiplier")
+			}
+		}()
+	}
+
+	// Players cash out one bet, or all of theirs for odd users, while the round ticks
+	done := make(chan struct{})
+	for u := 1; u <= users; u++ {
+		players.Add(1)
+		go func(userID int64) {
+			defer players.Done()
+			rng := rand.New(rand.NewSource(userID))
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if userID%2 == 1 && rng.Intn(20) == 0 {
+					claimed, _, err := e.CashoutAll(userID)
+					if err == nil {
+						for _, bet := range claimed {
+							settles.settle(t, e, bet, "User")
+						}
+					}
+				} else {
+					betID := int64(rng.Intn(bets) + 1)
+					if bet, _, err := e.Cashout(userID, betID); err == nil {
+						claims <- bet
+					}
+				}
+				time.Sleep(time.Duration(rng.Intn(500)) * time.Microsecond)
+			}
+		}(int64(u))
+	}
+
+	for {
+		due, _, crashed := e.Tick(time.Now())
+		for _, bet := range due {
+			claims <- bet
+		}
+		if crashed {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(done)
+	players.Wait()
+	close(claims)
+	settlers.Wait()
+
+	waited := make(chan struct{})
+	go func() {
+		e.WaitSettled()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitSettled did not return with every claim settled")
+	}
+
+	won := 0
+	for _, bet := range e.AllBets() {
+		n := settles.counts[bet.ID]
+		switch bet.State {
+		case models.BetWon:
+			won++
+			if n != 1 {
+				t.Errorf("bet %d won, settled %d times", bet.ID, n)
+			}
+		case models.BetLost:
+			if n != 0 {
+				t.Errorf("bet %d lost, settled %d times", bet.ID, n)
+			}
+		default:
+			t.Errorf("bet %d left %s", bet.ID, bet.State)
+		}
+	}
+	if won == 0 || won == bets {
+		t.Fatalf("%d of %d bets won, the round should split them", won, bets)
+	}
+}
+
+func TestClaimKeepsEarnedMultiplierAfterCrash(t *testing.T) {
+	e, start := seedRound(t, 1, 1, 1.5, 100)
+	bet := e.AllBets()[0]
+
+	now := start.Add(elapsedFor(bet.Multiplier))
+	due, _, crashed := e.Tick(now)
+	if len(due) != 1 || crashed {
+		t.Fatalf("due %v crashed %v, want the bet due before the crash", due, crashed)
+	}
+	if _, _, crashed = e.Tick(now.Add(time.Hour)); !crashed {
+		t.Fatal("round did not crash")
+	}
+
+	// The credit failed meanwhile, the bet is still settling and is paid once recorded
+	waited := make(chan struct{})
+	go func() {
+		e.WaitSettled()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("WaitSettled returned with a claim pending")
+	case <-time.After(20 * time.Millisecond):
+	}
+	settled, ok := e.Settle(bet.UserID, bet.ID, bet.Bet*bet.Multiplier, "Multiplier", bet.Multiplier)
+	if !ok || settled.State != models.BetWon || settled.CheckoutOn != bet.Multiplier {
+		t.Fatalf("settle after crash = %+v, %v", settled, ok)
+	}
+	<-waited
+	if _, ok := e.Settle(bet.UserID, bet.ID, 1, "Multiplier", 1); ok {
+		t.Fatal("bet settled twice")
+	}
+}
+
+func TestAbandonReleasesTheRound(t *testing.T) {
+	e, start := seedRound(t, 1, 1, 1.5, 100)
+	bet := e.AllBets()[0]
+
+	if _, ok := e.Abandon(bet.UserID, bet.ID, 15, "Multiplier", 1.5); ok {
+		t.Fatal("open bet abandoned")
+	}
+	due, _, _ := e.Tick(start.Add(elapsedFor(bet.Multiplier)))
+	if len(due) != 1 {
+		t.Fatalf("due %v, want the bet", due)
+	}
+
+	unpaid, ok := e.Abandon(bet.UserID, bet.ID, 15, "Multiplier", 1.5)
+	if !ok || unpaid.State != models.BetUnpaid || unpaid.Payout != 15 || unpaid.CheckoutOn != 1.5 {
+		t.Fatalf("abandon = %+v, %v", unpaid, ok)
+	}
+	e.WaitSettled()
+	if _, ok := e.Settle(bet.UserID, bet.ID, 15, "Multiplier", 1.5); ok {
+		t.Fatal("unpaid bet settled")
+	}
+}
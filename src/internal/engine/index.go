@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"container/heap"
+	"math"
+)
+
+// cashoutEntry is a bet waiting for its auto-cashout
+type cashoutEntry struct {
+	trigger float64 // multiplier at which the bet is due
+	betID   int64
+	userID  int64
+}
+
+// cashoutIndex is a min-heap of live bets by trigger multiplier, ties by bet ID
+type cashoutIndex []cashoutEntry
+
+func (h cashoutIndex) Len() int { return len(h) }
+func (h cashoutIndex) Less(i, j int) bool {
+	if h[i].trigger != h[j].trigger {
+		return h[i].trigger < h[j].trigger
+	}
+	return h[i].betID < h[j].betID
+}
+func (h cashoutIndex) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *cashoutIndex) Push(x any)   { *h = append(*h, x.(cashoutEntry)) }
+func (h *cashoutIndex) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	*h = old[:n-1]
+	return e
+}
+
+// cashoutTrigger is the first multiplier at which a bet is due: its target, or
+// earlier once its payout reaches the max win
+func cashoutTrigger(stake, target, maxWin float64) float64 {
+	if maxWin > 0 && stake > 0 {
+		return math.Min(target, maxWin/stake)
+	}
+	return target
+}
+
+// push indexes a bet
+func (h *cashoutIndex) push(e cashoutEntry) {
+	heap.Push(h, e)
+}
+
+// popDue removes and returns the entries due at multiplier, lowest trigger first
+func (h *cashoutIndex) popDue(multiplier float64) []cashoutEntry {
+	var due []cashoutEntry
+	for h.Len() > 0 && (*h)[0].trigger <= multiplier {
+		due = append(due, heap.Pop(h).(cashoutEntry))
+	}
+	return due
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/risk"
+)
+
+// testLimits never reject a bet of the tests
+var testLimits = risk.Policy{MaxBet: 1e9, MaxUserBets: 1e6, MaxUserTotal: 1e12, MaxRoundTotal: 1e15}
+
+// seedRound opens a round with n bets of users users, auto-cashouts between minTarget and
+// 10, and starts it with the crash point at crashAt
+func seedRound(tb testing.TB, n, users int, minTarget, crashAt float64) (*GameEngine, time.Time) {
+	tb.Helper()
+	rng := rand.New(rand.NewSource(1))
+	e := New()
+	e.Open(models.LiveGame{ID: 1, Limits: testLimits})
+	for i := 1; i <= n; i++ {
+		target := minTarget + rng.Float64()*(10-minTarget)
+		bet := models.Bet{
+			ID:         int64(i),
+			UserID:     int64(i%users + 1),
+			GameID:     1,
+			Bet:        1,
+			Multiplier: float64(int(target*100)) / 100,
+		}
+		if err := e.PlaceBet(bet); err != nil {
+			tb.Fatalf("place bet %d: %v", i, err)
+		}
+	}
+	e.CloseBetting()
+	e.SetCrashPoint("", crashAt)
+	start := time.Unix(0, 0)
+	e.Start(Curve{Rate: DefaultCurveRate, Tick: DefaultTick}, start)
+	return e, start
+}
+
+// elapsedFor is the time since start at which the curve reaches multiplier
+func elapsedFor(multiplier float64) time.Duration {
+	ms := int64(0)
+	for (Curve{Rate: DefaultCurveRate}).MultiplierAt(ms) < multiplier {
+		ms += 10
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+func TestTickPaysDueBetsInTriggerOrder(t *testing.T) {
+	e, start := seedRound(t, 1000, 1000, 1.01, 100)
+
+	last := 0.
+	paid := 0
+	for now := start; ; now = now.Add(DefaultTick) {
+		due, multiplier, crashed := e.Tick(now)
+		for _, bet := range due {
+			if bet.Multiplier > multiplier {
+				t.Fatalf("bet %d due at %.2f before its target %.2f", bet.ID, multiplier, bet.Multiplier)
+			}
+			if bet.Multiplier < last {
+				t.Fatalf("bet %d target %.2f after %.2f", bet.ID, bet.Multiplier, last)
+			}
+			last = bet.Multiplier
+			paid++
+		}
+		if crashed || paid == 1000 {
+			break
+		}
+	}
+	if paid != 1000 {
+		t.Fatalf("%d bets due, want 1000", paid)
+	}
+}
+
+// BenchmarkTick is one tick of a round with 10k live bets and none due
+func BenchmarkTick(b *testing.B) {
+	e, start := seedRound(b, 10000, 2500, 2, 100)
+	now := start.Add(elapsedFor(1.5))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if due, _, _ := e.Tick(now); len(due) > 0 {
+			b.Fatalf("%d bets due", len(due))
+		}
+	}
+}
+
+// BenchmarkTickRound ticks a round of 10k bets until every auto-cashout was due
+func BenchmarkTickRound(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		e, start := seedRound(b, 10000, 2500, 1.01, 100)
+		b.StartTimer()
+		paid := 0
+		for now := start; paid < 10000; now = now.Add(DefaultTick) {
+			due, _, _ := e.Tick(now)
+			paid += len(due)
+		}
+	}
+}
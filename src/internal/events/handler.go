@@ -3,6 +3,7 @@ package events
 type Event struct {
 	Target string
 	UserID int64
+	Room   string
 	Type   string
 	Data   interface{}
 }
@@ -21,3 +22,18 @@ func Emit(target string, eventType string, data interface{}) {
 
 	}
 }
+
+// EmitRoom sends an event to the clients of a room
+func EmitRoom(room string, eventType string, data interface{}) {
+	ev := Event{
+		Target: "room",
+		Room:   room,
+		Type:   eventType,
+		Data:   data,
+	}
+	select {
+	case Bus <- ev:
+	default:
+
+	}
+}
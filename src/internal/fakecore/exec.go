@@ -0,0 +1,670 @@
+package fakecore
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type result struct {
+	isSelect   bool
+	insertedID int64
+	affected   int64
+	columns    []string
+	rows       [][]value
+}
+
+// scope is a row being evaluated, columns are keyed by alias.col and by col.
+// An unqualified column of a join resolves to the first source that has it.
+type scope map[string]value
+
+type executor struct {
+	store  *Store
+	params []value
+	now    time.Time
+}
+
+func tableScope(alias string, t *table, row map[string]value) scope {
+	sc := make(scope, 2*len(t.schema.columns))
+	for _, col := range t.schema.columns {
+		sc[alias+"."+col] = row[col]
+		sc[col] = row[col]
+	}
+	return sc
+}
+
+/* Select */
+
+func (x *executor) selectRows(st *selectStmt) result {
+	rows := []scope{{}}
+	var columns []string // of the sources, for *
+	if st.from != nil {
+		rows, columns = x.sourceRows(*st.from)
+	}
+	for _, j := range st.joins {
+		right, cols := x.sourceRows(j.src)
+		columns = append(columns, cols...)
+		var joined []scope
+		for _, l := range rows {
+			for _, r := range right {
+				sc := make(scope, len(l)+len(r))
+				for k, v := range r {
+					sc[k] = v
+				}
+				for k, v := range l {
+					sc[k] = v
+				}
+				if truth(x.eval(j.on, sc, nil)) {
+					joined = append(joined, sc)
+				}
+			}
+		}
+		rows = joined
+	}
+	if st.where != nil {
+		var kept []scope
+		for _, sc := range rows {
+			if truth(x.eval(st.where, sc, nil)) {
+				kept = append(kept, sc)
+			}
+		}
+		rows = kept
+	}
+
+	res := result{}
+	for _, it := range st.items {
+		if it.star {
+			for _, col := range columns {
+				if it.table == "" || strings.HasPrefix(col, it.table+".") {
+					res.columns = append(res.columns, col[strings.IndexByte(col, '.')+1:])
+				}
+			}
+			continue
+		}
+		res.columns = append(res.columns, it.name)
+	}
+
+	// Aggregates without GROUP BY fold every row into one
+	aggregate := false
+	for _, it := range st.items {
+		if !it.star && hasAggregate(it.x) {
+			aggregate = true
+		}
+	}
+	if aggregate {
+		first := scope{}
+		if len(rows) > 0 {
+			first = rows[0]
+		}
+		res.rows = [][]value{x.project(st, columns, first, rows)}
+		return res
+	}
+
+	if len(st.order) > 0 {
+		keys := make([][]value, len(rows))
+		for i, sc := range rows {
+			for _, o := range st.order {
+				keys[i] = append(keys[i], x.eval(o.x, sc, nil))
+			}
+		}
+		idx := make([]int, len(rows))
+		for i := range idx {
+			idx[i] = i
+		}
+		sort.SliceStable(idx, func(a, b int) bool {
+			for k, o := range st.order {
+				c := order(keys[idx[a]][k], keys[idx[b]][k])
+				if c != 0 {
+					return (c < 0) != o.desc
+				}
+			}
+			return false
+		})
+		sorted := make([]scope, len(rows))
+		for i, j := range idx {
+			sorted[i] = rows[j]
+		}
+		rows = sorted
+	}
+	if st.limit != nil {
+		n, ok := toInt(x.eval(st.limit, scope{}, nil))
+		if !ok || n < 0 {
+			fail("invalid LIMIT")
+		}
+		if int(n) < len(rows) {
+			rows = rows[:n]
+		}
+	}
+	for _, sc := range rows {
+		res.rows = append(res.rows, x.project(st, columns, sc, nil))
+	}
+	return res
+}
+
+func (x *executor) project(st *selectStmt, columns []string, sc scope, group []scope) []value {
+	var out []value
+	for _, it := range st.items {
+		if it.star {
+			for _, col := range columns {
+				if it.table == "" || strings.HasPrefix(col, it.table+".") {
+					out = append(out, sc[col])
+				}
+			}
+			continue
+		}
+		out = append(out, x.eval(it.x, sc, group))
+	}
+	return out
+}
+
+// sourceRows returns the rows of a table or subquery and their alias.col names
+func (x *executor) sourceRows(src source) ([]scope, []string) {
+	var (
+		rows    []scope
+		columns []string
+	)
+	if src.sub != nil {
+		sub := x.selectRows(src.sub)
+		for _, col := range sub.columns {
+			columns = append(columns, src.alias+"."+col)
+		}
+		for _, r := range sub.rows {
+			sc := make(scope, 2*len(r))
+			for i, col := range sub.columns {
+				sc[src.alias+"."+col] = r[i]
+				sc[col] = r[i]
+			}
+			rows = append(rows, sc)
+		}
+		return rows, columns
+	}
+	t := x.store.table(src.table)
+	for _, col := range t.schema.columns {
+		columns = append(columns, src.alias+"."+col)
+	}
+	for _, row := range t.rows {
+		rows = append(rows, tableScope(src.alias, t, row))
+	}
+	return rows, columns
+}
+
+func hasAggregate(n node) bool {
+	switch t := n.(type) {
+	case funcNode:
+		switch t.name {
+		case "MAX", "MIN", "AVG", "SUM", "COUNT":
+			return true
+		}
+		for _, a := range t.args {
+			if hasAggregate(a) {
+				return true
+			}
+		}
+	case binNode:
+		return hasAggregate(t.l) || hasAggregate(t.r)
+	case notNode:
+		return hasAggregate(t.x)
+	case negNode:
+		return hasAggregate(t.x)
+	}
+	return false
+}
+
+/* Writes */
+
+func (x *executor) insert(st *insertStmt) result {
+	t := x.store.table(st.table)
+	row := map[string]value{}
+	for col, def := range t.schema.defaults {
+		if _, ok := def.(currentTimestamp); ok {
+			def = x.now
+		}
+		row[col] = def
+	}
+	for i, col := range st.cols {
+		if !t.hasColumn(col) {
+			fail("Unknown column '%s' in 'field list'", col)
+		}
+		row[col] = x.eval(st.values[i], scope{}, nil)
+	}
+	if id, ok := toInt(row["id"]); ok && id > 0 {
+		row["id"] = id
+	} else {
+		row["id"] = t.autoID + 1
+	}
+
+	for _, key := range append([]string{"id"}, t.schema.unique...) {
+		for _, existing := range t.rows {
+			if row[key] == nil || order(existing[key], row[key]) != 0 {
+				continue
+			}
+			if st.onDup == nil {
+				fail("Duplicate entry '%v' for key '%s'", row[key], key)
+			}
+			// MySQL reports 2 for an updated row and 0 for an unchanged one
+			if x.assign(t, existing, st.onDup) {
+				return result{insertedID: existing["id"].(int64), affected: 2}
+			}
+			return result{}
+		}
+	}
+
+	id := row["id"].(int64)
+	if id > t.autoID {
+		t.autoID = id
+	}
+	t.rows = append(t.rows, row)
+	return result{insertedID: id, affected: 1}
+}
+
+func (x *executor) update(st *updateStmt) result {
+	t := x.store.table(st.table)
+	res := result{}
+	for _, row := range t.rows {
+		if st.where != nil && !truth(x.eval(st.where, tableScope(t.name, t, row), nil)) {
+			continue
+		}
+		// Like MySQL without CLIENT_FOUND_ROWS, only changed rows are affected
+		if x.assign(t, row, st.sets) {
+			res.affected++
+		}
+	}
+	return res
+}
+
+// assign applies SET assignments left to right and reports whether the row changed
+func (x *executor) assign(t *table, row map[string]value, sets []assign) bool {
+	changed := false
+	for _, a := range sets {
+		if !t.hasColumn(a.col) {
+			fail("Unknown column '%s' in 'field list'", a.col)
+		}
+		v := x.eval(a.x, tableScope(t.name, t, row), nil)
+		if !same(row[a.col], v) {
+			row[a.col] = v
+			changed = true
+		}
+	}
+	if changed {
+		for _, col := range t.schema.onUpdate {
+			row[col] = x.now
+		}
+	}
+	return changed
+}
+
+func (x *executor) delete(st *deleteStmt) result {
+	t := x.store.table(st.table)
+	res := result{}
+	kept := t.rows[:0]
+	for _, row := range t.rows {
+		if st.where == nil || truth(x.eval(st.where, tableScope(t.name, t, row), nil)) {
+			res.affected++
+			continue
+		}
+		kept = append(kept, row)
+	}
+	t.rows = kept
+	return res
+}
+
+/* Expressions */
+
+// eval computes an expression on a row, group holds the rows folded by aggregates
+func (x *executor) eval(n node, sc scope, group []scope) value {
+	switch t := n.(type) {
+	case litNode:
+		return t.v
+	case paramNode:
+		return x.params[t.i]
+	case colNode:
+		key := t.name
+		if t.table != "" {
+			key = t.table + "." + t.name
+		}
+		v, ok := sc[key]
+		if !ok {
+			fail("Unknown column '%s'", key)
+		}
+		return v
+	case notNode:
+		v := x.eval(t.x, sc, group)
+		if v == nil {
+			return nil
+		}
+		return boolValue(!truth(v))
+	case negNode:
+		return arith("-", int64(0), x.eval(t.x, sc, group))
+	case isNullNode:
+		return boolValue((x.eval(t.x, sc, group) == nil) != t.not)
+	case inNode:
+		v := x.eval(t.x, sc, group)
+		if v == nil {
+			return nil
+		}
+		found := false
+		for _, item := range t.list {
+			if c, ok := compare(v, x.eval(item, sc, group)); ok && c == 0 {
+				found = true
+				break
+			}
+		}
+		return boolValue(found != t.not)
+	case betweenNode:
+		v := x.eval(t.x, sc, group)
+		lo, okLo := compare(v, x.eval(t.lo, sc, group))
+		hi, okHi := compare(v, x.eval(t.hi, sc, group))
+		if !okLo || !okHi {
+			return nil
+		}
+		return boolValue((lo >= 0 && hi <= 0) != t.not)
+	case binNode:
+		return x.binary(t, sc, group)
+	case funcNode:
+		return x.call(t, sc, group)
+	case intervalNode:
+		fail("INTERVAL outside DATE_ADD")
+	}
+	fail("unsupported expression %T", n)
+	return nil
+}
+
+func (x *executor) binary(t binNode, sc scope, group []scope) value {
+	switch t.op {
+	case "AND":
+		l, r := x.eval(t.l, sc, group), x.eval(t.r, sc, group)
+		if l != nil && !truth(l) || r != nil && !truth(r) {
+			return int64(0)
+		}
+		if l == nil || r == nil {
+			return nil
+		}
+		return int64(1)
+	case "OR":
+		l, r := x.eval(t.l, sc, group), x.eval(t.r, sc, group)
+		if l != nil && truth(l) || r != nil && truth(r) {
+			return int64(1)
+		}
+		if l == nil || r == nil {
+			return nil
+		}
+		return int64(0)
+	case "+", "-", "*", "/":
+		return arith(t.op, x.eval(t.l, sc, group), x.eval(t.r, sc, group))
+	}
+	c, ok := compare(x.eval(t.l, sc, group), x.eval(t.r, sc, group))
+	if !ok {
+		return nil
+	}
+	switch t.op {
+	case "=":
+		return boolValue(c == 0)
+	case "<>":
+		return boolValue(c != 0)
+	case "<":
+		return boolValue(c < 0)
+	case "<=":
+		return boolValue(c <= 0)
+	case ">":
+		return boolValue(c > 0)
+	default: // >=
+		return boolValue(c >= 0)
+	}
+}
+
+func (x *executor) call(f funcNode, sc scope, group []scope) value {
+	switch f.name {
+	case "NOW", "CURRENT_TIMESTAMP", "UTC_TIMESTAMP":
+		return x.now
+	case "VERSION":
+		return "8.0.0-fakecore"
+	case "DATE_ADD", "DATE_SUB":
+		if len(f.args) != 2 {
+			fail("%s needs 2 arguments", f.name)
+		}
+		iv, ok := f.args[1].(intervalNode)
+		if !ok {
+			fail("%s needs an INTERVAL", f.name)
+		}
+		at, ok := toTime(x.eval(f.args[0], sc, group))
+		n, okN := toFloat(x.eval(iv.n, sc, group))
+		if !ok || !okN {
+			return nil
+		}
+		if f.name == "DATE_SUB" {
+			n = -n
+		}
+		unit := map[string]time.Duration{
+			"SECOND": time.Second, "MINUTE": time.Minute, "HOUR": time.Hour, "DAY": 24 * time.Hour,
+		}[iv.unit]
+		if unit == 0 {
+			fail("unsupported INTERVAL unit %s", iv.unit)
+		}
+		return at.Add(time.Duration(n * float64(unit)))
+	case "COALESCE", "IFNULL":
+		for _, a := range f.args {
+			if v := x.eval(a, sc, group); v != nil {
+				return v
+			}
+		}
+		return nil
+	case "COUNT":
+		n := int64(0)
+		for _, row := range group {
+			if f.star || x.eval(f.args[0], row, nil) != nil {
+				n++
+			}
+		}
+		return n
+	case "MAX", "MIN", "SUM", "AVG":
+		if len(f.args) != 1 {
+			fail("%s needs 1 argument", f.name)
+		}
+		var (
+			acc   value
+			sum   float64
+			n     int
+			isInt = true
+		)
+		for _, row := range group {
+			v := x.eval(f.args[0], row, nil)
+			if v == nil {
+				continue
+			}
+			n++
+			if fv, ok := toFloat(v); ok {
+				sum += fv
+			}
+			if _, ok := v.(int64); !ok {
+				isInt = false
+			}
+			if acc == nil {
+				acc = v
+				continue
+			}
+			if c := order(v, acc); f.name == "MAX" && c > 0 || f.name == "MIN" && c < 0 {
+				acc = v
+			}
+		}
+		if n == 0 {
+			return nil
+		}
+		switch f.name {
+		case "SUM":
+			if isInt {
+				return int64(sum)
+			}
+			return sum
+		case "AVG":
+			return sum / float64(n)
+		}
+		return acc
+	}
+	fail("FUNCTION %s does not exist", f.name)
+	return nil
+}
+
+/* Values */
+
+func boolValue(b bool) value {
+	if b {
+		return int64(1)
+	}
+	return int64(0)
+}
+
+func truth(v value) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case time.Time:
+		return true
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f != 0
+	}
+	f, _ := toFloat(v)
+	return f != 0
+}
+
+func toFloat(v value) (float64, bool) {
+	switch t := v.(type) {
+	case int64:
+		return float64(t), true
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toInt(v value) (int64, bool) {
+	if i, ok := v.(int64); ok {
+		return i, true
+	}
+	f, ok := toFloat(v)
+	return int64(math.Round(f)), ok
+}
+
+func toTime(v value) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		for _, layout := range []string{DateTimeLayout, "2006-01-02", time.RFC3339} {
+			if at, err := time.Parse(layout, t); err == nil {
+				return at, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// compare orders two non-NULL values, MySQL style: numbers as numbers, a DATETIME with a
+// string as times, anything else as strings
+func compare(a, b value) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := toTime(b); ok {
+			return ta.Compare(tb), true
+		}
+	}
+	if tb, ok := b.(time.Time); ok {
+		if ta, ok := toTime(a); ok {
+			return ta.Compare(tb), true
+		}
+	}
+	_, aStr := a.(string)
+	_, bStr := b.(string)
+	if aStr && bStr {
+		return strings.Compare(a.(string), b.(string)), true
+	}
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if !okA || !okB {
+		return strings.Compare(text(a), text(b)), true
+	}
+	switch {
+	case fa < fb:
+		return -1, true
+	case fa > fb:
+		return 1, true
+	}
+	return 0, true
+}
+
+// order sorts NULL first, like MySQL ascending
+func order(a, b value) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	c, _ := compare(a, b)
+	return c
+}
+
+// same tells whether an assignment leaves a column unchanged
+func same(a, b value) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	c, _ := compare(a, b)
+	return c == 0
+}
+
+func arith(op string, a, b value) value {
+	if a == nil || b == nil {
+		return nil
+	}
+	ia, aInt := a.(int64)
+	ib, bInt := b.(int64)
+	if aInt && bInt && op != "/" {
+		switch op {
+		case "+":
+			return ia + ib
+		case "-":
+			return ia - ib
+		default:
+			return ia * ib
+		}
+	}
+	fa, _ := toFloat(a)
+	fb, _ := toFloat(b)
+	switch op {
+	case "+":
+		return fa + fb
+	case "-":
+		return fa - fb
+	case "*":
+		return fa * fb
+	}
+	if fb == 0 {
+		return nil
+	}
+	return fa / fb
+}
+
+// text is how a value is sent, DECIMAL and DATETIME columns come as strings from Core
+func text(v value) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case time.Time:
+		return t.UTC().Format(DateTimeLayout)
+	}
+	return ""
+}
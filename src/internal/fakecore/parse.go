@@ -0,0 +1,649 @@
+package fakecore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The parser covers the MySQL subset G2 sends: single-table INSERT (with ON DUPLICATE
+// KEY UPDATE), UPDATE and DELETE, and SELECT with a table or subquery source, inner
+// joins, WHERE, ORDER BY, LIMIT and aggregates without GROUP BY.
+
+type tokKind int
+
+const (
+	tEOF tokKind = iota
+	tIdent
+	tNumber
+	tString
+	tParam
+	tPunct
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int // start in the SQL
+	end  int
+}
+
+// sqlError is raised by the parser and the executor, recovered per statement
+type sqlError struct {
+	msg string
+}
+
+func (e *sqlError) Error() string {
+	return e.msg
+}
+
+func fail(format string, args ...any) {
+	panic(&sqlError{msg: fmt.Sprintf(format, args...)})
+}
+
+// catch turns a raised sqlError into err, other panics go on
+func catch(err *error) {
+	if r := recover(); r != nil {
+		e, ok := r.(*sqlError)
+		if !ok {
+			panic(r)
+		}
+		*err = e
+	}
+}
+
+func lex(sql string) []token {
+	var toks []token
+	i := 0
+	for i < len(sql) {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isIdentChar(c) && (c < '0' || c > '9'):
+			j := i
+			for j < len(sql) && isIdentChar(sql[j]) {
+				j++
+			}
+			toks = append(toks, token{kind: tIdent, text: sql[i:j], pos: i, end: j})
+			i = j
+		case c == '`':
+			j := strings.IndexByte(sql[i+1:], '`')
+			if j < 0 {
+				fail("unterminated identifier at %d", i)
+			}
+			toks = append(toks, token{kind: tIdent, text: sql[i+1 : i+1+j], pos: i, end: i + j + 2})
+			i += j + 2
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(sql) && (sql[j] >= '0' && sql[j] <= '9' || sql[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{kind: tNumber, text: sql[i:j], pos: i, end: j})
+			i = j
+		case c == '\'' || c == '"':
+			var sb strings.Builder
+			j := i + 1
+			for {
+				if j >= len(sql) {
+					fail("unterminated string at %d", i)
+				}
+				if sql[j] == '\\' && j+1 < len(sql) {
+					sb.WriteByte(sql[j+1])
+					j += 2
+					continue
+				}
+				if sql[j] == c {
+					if j+1 < len(sql) && sql[j+1] == c {
+						sb.WriteByte(c)
+						j += 2
+						continue
+					}
+					break
+				}
+				sb.WriteByte(sql[j])
+				j++
+			}
+			toks = append(toks, token{kind: tString, text: sb.String(), pos: i, end: j + 1})
+			i = j + 1
+		case c == '?':
+			toks = append(toks, token{kind: tParam, text: "?", pos: i, end: i + 1})
+			i++
+		default:
+			if i+1 < len(sql) {
+				if two := sql[i : i+2]; two == "<=" || two == ">=" || two == "<>" || two == "!=" {
+					toks = append(toks, token{kind: tPunct, text: two, pos: i, end: i + 2})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("(),.*+-/=<>;", rune(c)) {
+				fail("unexpected %q at %d", c, i)
+			}
+			toks = append(toks, token{kind: tPunct, text: string(c), pos: i, end: i + 1})
+			i++
+		}
+	}
+	return append(toks, token{kind: tEOF, pos: len(sql), end: len(sql)})
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+/* AST */
+
+type node interface{}
+
+type (
+	litNode   struct{ v value }
+	paramNode struct{ i int }
+	colNode   struct{ table, name string }
+	binNode   struct {
+		op   string
+		l, r node
+	}
+	notNode struct{ x node }
+	negNode struct{ x node }
+	inNode  struct {
+		x    node
+		list []node
+		not  bool
+	}
+	betweenNode struct {
+		x, lo, hi node
+		not       bool
+	}
+	isNullNode struct {
+		x   node
+		not bool
+	}
+	funcNode struct {
+		name string // upper case
+		args []node
+		star bool // COUNT(*)
+	}
+	intervalNode struct {
+		n    node
+		unit string // upper case
+	}
+)
+
+type selItem struct {
+	x     node
+	name  string // alias or the item's SQL
+	star  bool
+	table string // t.* qualifier
+}
+
+type source struct {
+	table string
+	alias string
+	sub   *selectStmt
+}
+
+type join struct {
+	src source
+	on  node
+}
+
+type orderItem struct {
+	x    node
+	desc bool
+}
+
+type assign struct {
+	col string
+	x   node
+}
+
+type selectStmt struct {
+	items []selItem
+	from  *source
+	joins []join
+	where node
+	order []orderItem
+	limit node
+}
+
+type insertStmt struct {
+	table  string
+	cols   []string
+	values []node
+	onDup  []assign
+}
+
+type updateStmt struct {
+	table string
+	sets  []assign
+	where node
+}
+
+type deleteStmt struct {
+	table string
+	where node
+}
+
+/* Parser */
+
+// Words that end an expression, they never start an implicit alias
+var reserved = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "JOIN": true, "INNER": true, "ON": true,
+	"ORDER": true, "BY": true, "LIMIT": true, "AND": true, "OR": true, "NOT": true, "AS": true,
+	"SET": true, "VALUES": true, "DUPLICATE": true, "IN": true, "BETWEEN": true, "IS": true,
+	"NULL": true, "GROUP": true, "ASC": true, "DESC": true,
+}
+
+type parser struct {
+	sql    string
+	toks   []token
+	i      int
+	params int
+}
+
+// parse reads one statement and counts its ? placeholders
+func parse(sql string) (stmt any, params int, err error) {
+	defer catch(&err)
+	p := &parser{sql: sql, toks: lex(sql)}
+	switch {
+	case p.isKw("SELECT"):
+		stmt = p.selectStmt()
+	case p.isKw("INSERT"):
+		stmt = p.insertStmt()
+	case p.isKw("UPDATE"):
+		stmt = p.updateStmt()
+	case p.isKw("DELETE"):
+		stmt = p.deleteStmt()
+	default:
+		fail("unsupported statement %q", p.peek().text)
+	}
+	p.acceptPunct(";")
+	if p.peek().kind != tEOF {
+		fail("unexpected %q at %d", p.peek().text, p.peek().pos)
+	}
+	return stmt, p.params, nil
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) isKw(kw string) bool {
+	t := p.peek()
+	return t.kind == tIdent && strings.EqualFold(t.text, kw)
+}
+
+func (p *parser) acceptKw(kw string) bool {
+	if p.isKw(kw) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKw(kw string) {
+	if !p.acceptKw(kw) {
+		fail("expected %s at %d, got %q", kw, p.peek().pos, p.peek().text)
+	}
+}
+
+func (p *parser) isPunct(s string) bool {
+	t := p.peek()
+	return t.kind == tPunct && t.text == s
+}
+
+func (p *parser) acceptPunct(s string) bool {
+	if p.isPunct(s) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectPunct(s string) {
+	if !p.acceptPunct(s) {
+		fail("expected %q at %d, got %q", s, p.peek().pos, p.peek().text)
+	}
+}
+
+func (p *parser) ident() string {
+	t := p.next()
+	if t.kind != tIdent {
+		fail("expected a name at %d, got %q", t.pos, t.text)
+	}
+	return t.text
+}
+
+// alias reads an optional [AS] name
+func (p *parser) alias() string {
+	if p.acceptKw("AS") {
+		return p.ident()
+	}
+	if t := p.peek(); t.kind == tIdent && !reserved[strings.ToUpper(t.text)] {
+		return p.ident()
+	}
+	return ""
+}
+
+func (p *parser) selectStmt() *selectStmt {
+	p.expectKw("SELECT")
+	s := &selectStmt{}
+	for {
+		s.items = append(s.items, p.selItem())
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+	if p.acceptKw("FROM") {
+		src := p.source()
+		s.from = &src
+		for {
+			p.acceptKw("INNER")
+			if !p.acceptKw("JOIN") {
+				break
+			}
+			j := join{src: p.source()}
+			p.expectKw("ON")
+			j.on = p.expr()
+			s.joins = append(s.joins, j)
+		}
+	}
+	if p.acceptKw("WHERE") {
+		s.where = p.expr()
+	}
+	if p.acceptKw("ORDER") {
+		p.expectKw("BY")
+		for {
+			o := orderItem{x: p.expr()}
+			if p.acceptKw("DESC") {
+				o.desc = true
+			} else {
+				p.acceptKw("ASC")
+			}
+			s.order = append(s.order, o)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+	}
+	if p.acceptKw("LIMIT") {
+		s.limit = p.expr()
+	}
+	return s
+}
+
+func (p *parser) selItem() selItem {
+	if p.acceptPunct("*") {
+		return selItem{star: true}
+	}
+	// t.*
+	if t := p.peek(); t.kind == tIdent && p.toks[p.i+1].text == "." && p.toks[p.i+2].text == "*" {
+		p.i += 3
+		return selItem{star: true, table: t.text}
+	}
+	start := p.peek().pos
+	x := p.expr()
+	name := p.sql[start:p.toks[p.i-1].end]
+	if c, ok := x.(colNode); ok {
+		name = c.name
+	}
+	if alias := p.alias(); alias != "" {
+		name = alias
+	}
+	return selItem{x: x, name: name}
+}
+
+func (p *parser) source() source {
+	if p.acceptPunct("(") {
+		sub := p.selectStmt()
+		p.expectPunct(")")
+		alias := p.alias()
+		if alias == "" {
+			fail("every derived table must have its own alias")
+		}
+		return source{sub: sub, alias: alias}
+	}
+	table := p.ident()
+	alias := p.alias()
+	if alias == "" {
+		alias = table
+	}
+	return source{table: table, alias: alias}
+}
+
+func (p *parser) insertStmt() *insertStmt {
+	p.expectKw("INSERT")
+	p.expectKw("INTO")
+	s := &insertStmt{table: p.ident()}
+	p.expectPunct("(")
+	for {
+		s.cols = append(s.cols, p.ident())
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+	p.expectPunct(")")
+	if !p.acceptKw("VALUES") {
+		p.expectKw("VALUE")
+	}
+	p.expectPunct("(")
+	for {
+		s.values = append(s.values, p.expr())
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+	p.expectPunct(")")
+	if len(s.cols) != len(s.values) {
+		fail("column count doesn't match value count")
+	}
+	if p.acceptKw("ON") {
+		p.expectKw("DUPLICATE")
+		p.expectKw("KEY")
+		p.expectKw("UPDATE")
+		s.onDup = p.assigns()
+	}
+	return s
+}
+
+func (p *parser) updateStmt() *updateStmt {
+	p.expectKw("UPDATE")
+	s := &updateStmt{table: p.ident()}
+	p.expectKw("SET")
+	s.sets = p.assigns()
+	if p.acceptKw("WHERE") {
+		s.where = p.expr()
+	}
+	return s
+}
+
+func (p *parser) deleteStmt() *deleteStmt {
+	p.expectKw("DELETE")
+	p.expectKw("FROM")
+	s := &deleteStmt{table: p.ident()}
+	if p.acceptKw("WHERE") {
+		s.where = p.expr()
+	}
+	return s
+}
+
+func (p *parser) assigns() []assign {
+	var list []assign
+	for {
+		col := p.ident()
+		if p.acceptPunct(".") {
+			col = p.ident()
+		}
+		p.expectPunct("=")
+		list = append(list, assign{col: col, x: p.expr()})
+		if !p.acceptPunct(",") {
+			return list
+		}
+	}
+}
+
+/* Expressions, lowest precedence first */
+
+func (p *parser) expr() node {
+	x := p.and()
+	for p.acceptKw("OR") {
+		x = binNode{op: "OR", l: x, r: p.and()}
+	}
+	return x
+}
+
+func (p *parser) and() node {
+	x := p.not()
+	for p.acceptKw("AND") {
+		x = binNode{op: "AND", l: x, r: p.not()}
+	}
+	return x
+}
+
+func (p *parser) not() node {
+	if p.acceptKw("NOT") {
+		return notNode{x: p.not()}
+	}
+	return p.cmp()
+}
+
+func (p *parser) cmp() node {
+	x := p.add()
+	if t := p.peek(); t.kind == tPunct {
+		switch t.text {
+		case "=", "<>", "!=", "<", "<=", ">", ">=":
+			p.i++
+			op := t.text
+			if op == "!=" {
+				op = "<>"
+			}
+			return binNode{op: op, l: x, r: p.add()}
+		}
+	}
+	if p.acceptKw("IS") {
+		not := p.acceptKw("NOT")
+		p.expectKw("NULL")
+		return isNullNode{x: x, not: not}
+	}
+	not := p.acceptKw("NOT")
+	switch {
+	case p.acceptKw("IN"):
+		n := inNode{x: x, not: not}
+		p.expectPunct("(")
+		for {
+			n.list = append(n.list, p.expr())
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+		p.expectPunct(")")
+		return n
+	case p.acceptKw("BETWEEN"):
+		n := betweenNode{x: x, lo: p.add(), not: not}
+		p.expectKw("AND")
+		n.hi = p.add()
+		return n
+	case not:
+		fail("expected IN or BETWEEN after NOT at %d", p.peek().pos)
+	}
+	return x
+}
+
+func (p *parser) add() node {
+	x := p.mul()
+	for p.isPunct("+") || p.isPunct("-") {
+		op := p.next().text
+		x = binNode{op: op, l: x, r: p.mul()}
+	}
+	return x
+}
+
+func (p *parser) mul() node {
+	x := p.unary()
+	for p.isPunct("*") || p.isPunct("/") {
+		op := p.next().text
+		x = binNode{op: op, l: x, r: p.unary()}
+	}
+	return x
+}
+
+func (p *parser) unary() node {
+	if p.acceptPunct("-") {
+		return negNode{x: p.unary()}
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() node {
+	t := p.next()
+	switch t.kind {
+	case tNumber:
+		if strings.Contains(t.text, ".") {
+			f, err := strconv.ParseFloat(t.text, 64)
+			if err != nil {
+				fail("invalid number %q", t.text)
+			}
+			return litNode{v: f}
+		}
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			fail("invalid number %q", t.text)
+		}
+		return litNode{v: n}
+	case tString:
+		return litNode{v: t.text}
+	case tParam:
+		p.params++
+		return paramNode{i: p.params - 1}
+	case tPunct:
+		if t.text == "(" {
+			x := p.expr()
+			p.expectPunct(")")
+			return x
+		}
+	case tIdent:
+		switch strings.ToUpper(t.text) {
+		case "NULL":
+			return litNode{v: nil}
+		case "TRUE":
+			return litNode{v: int64(1)}
+		case "FALSE":
+			return litNode{v: int64(0)}
+		case "INTERVAL":
+			n := p.add()
+			return intervalNode{n: n, unit: strings.ToUpper(p.ident())}
+		}
+		if p.acceptPunct("(") {
+			f := funcNode{name: strings.ToUpper(t.text)}
+			if p.acceptPunct("*") {
+				f.star = true
+			} else if !p.isPunct(")") {
+				for {
+					f.args = append(f.args, p.expr())
+					if !p.acceptPunct(",") {
+						break
+					}
+				}
+			}
+			p.expectPunct(")")
+			return f
+		}
+		if p.acceptPunct(".") {
+			return colNode{table: t.text, name: p.ident()}
+		}
+		if reserved[strings.ToUpper(t.text)] {
+			fail("unexpected %s at %d", t.text, t.pos)
+		}
+		return colNode{name: t.text}
+	}
+	fail("unexpected %q at %d", t.text, t.pos)
+	return nil
+}
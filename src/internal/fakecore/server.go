@@ -0,0 +1,187 @@
+package fakecore
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	pb "github.com/Milad-Abooali/4in-cs2skin-g2/src/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Server is a stand-in for the Core DataService on an in-memory Store, for local runs
+// and integration tests. Responses are shaped like Core's: writes report inserted_id and
+// rows_affected, selects report count and rows, failures have a non-ok status.
+type Server struct {
+	pb.UnimplementedDataServiceServer
+
+	Store *Store
+	token string
+	grpc  *grpc.Server
+	lis   net.Listener
+}
+
+// Start serves a new empty store on addr in the background, "127.0.0.1:0" picks a free
+// port. An empty token accepts every request.
+func Start(addr, token string) (*Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		Store: NewStore(),
+		token: token,
+		grpc:  grpc.NewServer(),
+		lis:   lis,
+	}
+	pb.RegisterDataServiceServer(s.grpc, s)
+	go func() {
+		_ = s.grpc.Serve(lis)
+	}()
+	return s, nil
+}
+
+// Addr is the address to give grpcclient.Connect
+func (s *Server) Addr() string {
+	return s.lis.Addr().String()
+}
+
+// Stop ends the in-flight calls, then the server
+func (s *Server) Stop() {
+	s.grpc.GracefulStop()
+}
+
+// Query runs one statement of any kind, as the legacy Core RPC does
+func (s *Server) Query(_ context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
+	if err := s.auth(req.Token); err != nil {
+		return failed(err), nil
+	}
+	return s.respond(req.Query, nil, anyStmt)
+}
+
+// Exec runs one write statement with bound parameters
+func (s *Server) Exec(_ context.Context, req *pb.ParamsRequest) (*pb.QueryResponse, error) {
+	if err := s.auth(req.Token); err != nil {
+		return failed(err), nil
+	}
+	params, err := paramValues(req.Params)
+	if err != nil {
+		return failed(err), nil
+	}
+	return s.respond(req.Query, params, writeStmt)
+}
+
+// QueryParams runs one SELECT with bound parameters
+func (s *Server) QueryParams(_ context.Context, req *pb.ParamsRequest) (*pb.QueryResponse, error) {
+	if err := s.auth(req.Token); err != nil {
+		return failed(err), nil
+	}
+	params, err := paramValues(req.Params)
+	if err != nil {
+		return failed(err), nil
+	}
+	return s.respond(req.Query, params, selectOnly)
+}
+
+// Transaction runs the statements together, nothing is kept when one fails
+func (s *Server) Transaction(_ context.Context, req *pb.TransactionRequest) (*pb.TransactionResponse, error) {
+	if err := s.auth(req.Token); err != nil {
+		return &pb.TransactionResponse{Status: "error", Error: err.Error()}, nil
+	}
+	stmts := make([]stmtParams, 0, len(req.Statements))
+	for _, st := range req.Statements {
+		params, err := paramValues(st.Params)
+		if err != nil {
+			return &pb.TransactionResponse{Status: "error", Error: err.Error()}, nil
+		}
+		stmts = append(stmts, stmtParams{sql: st.Query, params: params})
+	}
+	results, err := s.Store.transaction(stmts)
+	if err != nil {
+		return &pb.TransactionResponse{Status: "error", Error: err.Error()}, nil
+	}
+	res := &pb.TransactionResponse{Status: "ok"}
+	for _, r := range results {
+		res.Results = append(res.Results, resultData(r))
+	}
+	return res, nil
+}
+
+func (s *Server) auth(token string) error {
+	if s.token != "" && token != s.token {
+		return fmt.Errorf("invalid token")
+	}
+	return nil
+}
+
+// respond runs a statement of the kind
+func (s *Server) respond(sql string, params []any, kind string) (*pb.QueryResponse, error) {
+	s.Store.mu.Lock()
+	res, err := s.Store.run(sql, params, kind)
+	s.Store.mu.Unlock()
+	if err != nil {
+		return failed(err), nil
+	}
+	return &pb.QueryResponse{Status: "ok", Data: resultData(res)}, nil
+}
+
+func failed(err error) *pb.QueryResponse {
+	return &pb.QueryResponse{Status: "error", Error: err.Error()}
+}
+
+func paramValues(params []*pb.Param) ([]any, error) {
+	values := make([]any, len(params))
+	for i, p := range params {
+		switch v := p.GetValue().(type) {
+		case nil:
+			values[i] = nil
+		case *pb.Param_Int:
+			values[i] = v.Int
+		case *pb.Param_Float:
+			values[i] = v.Float
+		case *pb.Param_Text:
+			values[i] = v.Text
+		case *pb.Param_Bool:
+			values[i] = v.Bool
+		default:
+			return nil, fmt.Errorf("param %d: unsupported %T", i+1, v)
+		}
+	}
+	return values, nil
+}
+
+func resultData(r result) *structpb.Struct {
+	if !r.isSelect {
+		return &structpb.Struct{Fields: map[string]*structpb.Value{
+			"inserted_id":   structpb.NewNumberValue(float64(r.insertedID)),
+			"rows_affected": structpb.NewNumberValue(float64(r.affected)),
+		}}
+	}
+	rows := make([]*structpb.Value, len(r.rows))
+	for i, row := range r.rows {
+		fields := make(map[string]*structpb.Value, len(r.columns))
+		for j, col := range r.columns {
+			fields[col] = protoValue(row[j])
+		}
+		rows[i] = structpb.NewStructValue(&structpb.Struct{Fields: fields})
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		"count": structpb.NewNumberValue(float64(len(rows))),
+		"rows":  structpb.NewListValue(&structpb.ListValue{Values: rows}),
+	}}
+}
+
+// protoValue sends integers as numbers, DECIMAL and DATETIME values as strings
+func protoValue(v value) *structpb.Value {
+	switch t := v.(type) {
+	case nil:
+		return structpb.NewNullValue()
+	case int64:
+		return structpb.NewNumberValue(float64(t))
+	case float64, time.Time, string:
+		return structpb.NewStringValue(text(t))
+	}
+	return structpb.NewNullValue()
+}
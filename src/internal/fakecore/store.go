@@ -0,0 +1,262 @@
+package fakecore
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// value is a stored or computed SQL value: nil, int64, float64, string or time.Time.
+// Booleans are int64 1 and 0, like MySQL.
+type value = any
+
+// DateTimeLayout is how DATETIME values are read and sent, like Core
+const DateTimeLayout = "2006-01-02 15:04:05"
+
+// currentTimestamp marks a DEFAULT CURRENT_TIMESTAMP column
+type currentTimestamp struct{}
+
+// schema is the part of a table definition the fake needs
+type schema struct {
+	columns  []string
+	defaults map[string]value
+	unique   []string // besides id
+	onUpdate []string // ON UPDATE CURRENT_TIMESTAMP
+}
+
+var (
+	gamesSchema = schema{
+		columns: []string{"id", "server_seed", "server_seed_hash", "game", "is_live", "income", "expense", "roi", "he", "created_at"},
+		defaults: map[string]value{
+			"is_live": int64(1), "income": 0., "expense": 0., "roi": 0., "he": 0., "created_at": currentTimestamp{},
+		},
+	}
+	betsSchema = schema{
+		columns:  []string{"id", "user_id", "game_id", "bet", "created_at"},
+		defaults: map[string]value{"created_at": currentTimestamp{}},
+	}
+	// the DDL is in migrations/
+	outboxSchema = schema{
+		columns: []string{"id", "idem_key", "room", "kind", "user_id", "game_id", "bet_id", "tx_type", "reference_id",
+			"tx_ref", "amount", "status", "attempts", "last_error", "next_at", "created_at", "updated_at"},
+		defaults: map[string]value{
+			"attempts": int64(0), "last_error": "", "created_at": currentTimestamp{}, "updated_at": currentTimestamp{},
+		},
+		unique:   []string{"idem_key"},
+		onUpdate: []string{"updated_at"},
+	}
+	sagaSchema = schema{
+		columns:  []string{"id", "room", "game_id", "bet_id", "user_id", "step", "status", "detail", "created_at"},
+		defaults: map[string]value{"detail": "", "created_at": currentTimestamp{}},
+	}
+)
+
+// schemaOf matches a table name to the G2 schema, every room has <prefix>_games and <prefix>_bets
+func schemaOf(name string) (schema, bool) {
+	switch {
+	case name == "g2_outbox":
+		return outboxSchema, true
+	case name == "g2_bet_saga":
+		return sagaSchema, true
+	case strings.HasSuffix(name, "_games"):
+		return gamesSchema, true
+	case strings.HasSuffix(name, "_bets"):
+		return betsSchema, true
+	}
+	return schema{}, false
+}
+
+type table struct {
+	name   string
+	schema schema
+	autoID int64
+	rows   []map[string]value
+}
+
+func (t *table) clone() *table {
+	c := *t
+	c.rows = make([]map[string]value, len(t.rows))
+	for i, row := range t.rows {
+		c.rows[i] = cloneRow(row)
+	}
+	return &c
+}
+
+func cloneRow(row map[string]value) map[string]value {
+	c := make(map[string]value, len(row))
+	for k, v := range row {
+		c[k] = v
+	}
+	return c
+}
+
+func (t *table) hasColumn(col string) bool {
+	for _, c := range t.schema.columns {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// Store holds the tables of the fake Core in memory. Tables of the G2 schema are
+// created empty on first use, any other table does not exist.
+type Store struct {
+	mu     sync.Mutex
+	tables map[string]*table
+	now    func() time.Time
+}
+
+// NewStore returns an empty store on the wall clock
+func NewStore() *Store {
+	return &Store{tables: map[string]*table{}, now: time.Now}
+}
+
+// SetClock replaces the clock of NOW() and CURRENT_TIMESTAMP
+func (s *Store) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// Result is what a write reports
+type Result struct {
+	InsertedID   int64
+	RowsAffected int64
+}
+
+// Statement kinds an entry point accepts
+const (
+	anyStmt    = ""
+	writeStmt  = "write"
+	selectOnly = "select"
+)
+
+// Exec runs one write statement with its ? values
+func (s *Store) Exec(sql string, params ...any) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, err := s.run(sql, params, writeStmt)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{InsertedID: res.insertedID, RowsAffected: res.affected}, nil
+}
+
+// Query runs one SELECT with its ? values, rows are keyed by column
+func (s *Store) Query(sql string, params ...any) ([]map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, err := s.run(sql, params, selectOnly)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]map[string]any, len(res.rows))
+	for i, r := range res.rows {
+		rows[i] = make(map[string]any, len(res.columns))
+		for j, col := range res.columns {
+			rows[i][col] = r[j]
+		}
+	}
+	return rows, nil
+}
+
+// transaction runs the statements in order, the store is left as it was when one fails
+func (s *Store) transaction(stmts []stmtParams) ([]result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := make(map[string]*table, len(s.tables))
+	for name, t := range s.tables {
+		saved[name] = t.clone()
+	}
+	results := make([]result, 0, len(stmts))
+	for i, st := range stmts {
+		res, err := s.run(st.sql, st.params, writeStmt)
+		if err != nil {
+			s.tables = saved
+			return nil, fmt.Errorf("statement %d: %w", i+1, err)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+type stmtParams struct {
+	sql    string
+	params []any
+}
+
+// table returns a table of the G2 schema, creating it on first use. Lock must be held.
+func (s *Store) table(name string) *table {
+	if t, ok := s.tables[name]; ok {
+		return t
+	}
+	sc, ok := schemaOf(name)
+	if !ok {
+		fail("Table '%s' doesn't exist", name)
+	}
+	t := &table{name: name, schema: sc}
+	s.tables[name] = t
+	return t
+}
+
+// run parses and executes one statement of the kind. Lock must be held.
+func (s *Store) run(sql string, params []any, kind string) (res result, err error) {
+	stmt, n, err := parse(sql)
+	if err != nil {
+		return res, err
+	}
+	if _, isSelect := stmt.(*selectStmt); kind == writeStmt && isSelect || kind == selectOnly && !isSelect {
+		return res, fmt.Errorf("expected a %s statement", kind)
+	}
+	if n != len(params) {
+		return res, fmt.Errorf("%d placeholders for %d values", n, len(params))
+	}
+	values := make([]value, len(params))
+	for i, p := range params {
+		v, err := bindValue(p)
+		if err != nil {
+			return res, err
+		}
+		values[i] = v
+	}
+
+	defer catch(&err)
+	x := &executor{store: s, params: values, now: s.now().UTC().Truncate(time.Second)}
+	switch st := stmt.(type) {
+	case *selectStmt:
+		res = x.selectRows(st)
+		res.isSelect = true
+	case *insertStmt:
+		res = x.insert(st)
+	case *updateStmt:
+		res = x.update(st)
+	case *deleteStmt:
+		res = x.delete(st)
+	}
+	return res, nil
+}
+
+// bindValue converts a Go value to a stored value
+func bindValue(v any) (value, error) {
+	switch t := v.(type) {
+	case nil, int64, float64, string, time.Time:
+		return t, nil
+	case int:
+		return int64(t), nil
+	case int32:
+		return int64(t), nil
+	case float32:
+		return float64(t), nil
+	case []byte:
+		return string(t), nil
+	case bool:
+		if t {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	}
+	return nil, fmt.Errorf("cannot bind %T", v)
+}
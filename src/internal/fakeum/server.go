@@ -0,0 +1,388 @@
+package fakeum
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
+)
+
+// Request types of the UM API
+const (
+	GetJWT         = "xGetJWT"
+	GetUser        = "xGetUser"
+	AddTransaction = "xAddTransaction"
+	AddXp          = "xAddXp"
+)
+
+// Defaults of the app credentials in API_UM
+const (
+	DefaultAppToken = "fake-app-token"
+	DefaultXKey     = "fake-x-key"
+)
+
+// Errors the fake answers with, UM's own codes are not known to G2
+var (
+	ErrUnauthorized = Failure{Type: "UNAUTHORIZED", Code: 401}
+	ErrBadRequest   = Failure{Type: "BAD_REQUEST", Code: 400}
+	ErrInvalidToken = Failure{Type: "INVALID_TOKEN", Code: 4001}
+	ErrUserNotFound = Failure{Type: "USER_NOT_FOUND", Code: 4004}
+	ErrInsufficient = Failure{Type: "INSUFFICIENT_BALANCE", Code: 7001}
+)
+
+// DebitTypes are the transaction types that take the amount from the balance, any
+// other type adds it
+var DebitTypes = map[string]bool{
+	"game_loss":      true,
+	"req_withdrawal": true,
+}
+
+// User is a UM account, Token is the JWT that resolves to it
+type User struct {
+	ID          int
+	DisplayName string
+	Balance     float64
+	XP          int
+	Token       string
+}
+
+// Transaction is an applied xAddTransaction
+type Transaction struct {
+	utils.UMTransactionData
+	Balance float64 // after the transaction
+}
+
+// Xp is an applied xAddXp
+type Xp struct {
+	utils.UMXpData
+	XP int // after the change
+}
+
+// Failure is a UM error answer. Times limits it to the next n requests, 0 is every
+// request. Disconnect drops the connection instead of answering. Apply handles the
+// request before failing, like an answer lost on its way back.
+type Failure struct {
+	Type       string
+	Code       int
+	Data       any
+	Times      int
+	Disconnect bool
+	Apply      bool
+}
+
+// Server is a stand-in for the UM API on httptest, for local runs and end-to-end tests.
+// Answers are shaped like UM's: status 1 with data, or status 0 with error and type.
+//
+// One behaviour is an assumption, the UM contract does not document it: xAddXp applies a
+// negative amount, the AddBet saga reverts granted XP with one.
+type Server struct {
+	*httptest.Server
+
+	AppToken string
+	XKey     string
+
+	mu       sync.Mutex
+	users    map[int]*User
+	tokens   map[string]int
+	txs      []Transaction
+	xp       []Xp
+	failures map[string][]Failure
+	latency  map[string]time.Duration
+}
+
+// Start serves an empty UM with the default credentials
+func Start() *Server {
+	s := &Server{
+		AppToken: DefaultAppToken,
+		XKey:     DefaultXKey,
+		users:    map[int]*User{},
+		tokens:   map[string]int{},
+		failures: map[string][]Failure{},
+		latency:  map[string]time.Duration{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Env is the API_UM value that points G2 at the server
+func (s *Server) Env() string {
+	return fmt.Sprintf("%s, %s, %s", s.URL, s.AppToken, s.XKey)
+}
+
+// AddUser stores or replaces a user, its token resolves to it
+func (s *Server) AddUser(u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.users[u.ID]; ok && old.Token != "" {
+		delete(s.tokens, old.Token)
+	}
+	s.users[u.ID] = &u
+	if u.Token != "" {
+		s.tokens[u.Token] = u.ID
+	}
+}
+
+// User returns the current state of a user
+func (s *Server) User(id int) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return User{}, false
+	}
+	return *u, true
+}
+
+// SetBalance replaces the balance of a user
+func (s *Server) SetBalance(id int, balance float64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if ok {
+		u.Balance = balance
+	}
+	return ok
+}
+
+// Transactions returns the applied transactions in order
+func (s *Server) Transactions() []Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Transaction(nil), s.txs...)
+}
+
+// XpChanges returns the applied XP changes in order
+func (s *Server) XpChanges() []Xp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Xp(nil), s.xp...)
+}
+
+// Fail queues a failure for a request type, queued failures are answered in order
+func (s *Server) Fail(reqType string, f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[reqType] = append(s.failures[reqType], f)
+}
+
+// Delay holds every answer to a request type for d, 0 removes the delay
+func (s *Server) Delay(reqType string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency[reqType] = d
+}
+
+// Reset drops the queued failures and delays
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = map[string][]Failure{}
+	s.latency = map[string]time.Duration{}
+}
+
+// request is any UM request, data is decoded once the type is known
+type request struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil {
+		writeFailure(w, ErrBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	delay := s.latency[req.Type]
+	failure, failed := s.nextFailure(req.Type)
+	s.mu.Unlock()
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if failed && !failure.Apply {
+		failure.answer(w)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+s.AppToken {
+		writeFailure(w, ErrUnauthorized)
+		return
+	}
+
+	var (
+		data any
+		fail *Failure
+	)
+	switch req.Type {
+	case GetJWT:
+		var d utils.UMRequestData
+		if fail = s.decode(req.Data, &d, &d.XKey); fail == nil {
+			data, fail = s.getJWT(d.Token)
+		}
+	case GetUser:
+		var d utils.UMRequestData
+		if fail = s.decode(req.Data, &d, &d.XKey); fail == nil {
+			data, fail = s.getUser(d.UserID)
+		}
+	case AddTransaction:
+		var d utils.UMTransactionData
+		if fail = s.decode(req.Data, &d, &d.XKey); fail == nil {
+			data, fail = s.addTransaction(d)
+		}
+	case AddXp:
+		var d utils.UMXpData
+		if fail = s.decode(req.Data, &d, &d.XKey); fail == nil {
+			data, fail = s.addXp(d)
+		}
+	default:
+		fail = &ErrBadRequest
+	}
+	switch {
+	case failed:
+		failure.answer(w)
+	case fail != nil:
+		writeFailure(w, *fail)
+	default:
+		writeJSON(w, map[string]any{"status": 1, "data": data})
+	}
+}
+
+// answer sends the failure or drops the connection
+func (f Failure) answer(w http.ResponseWriter) {
+	if f.Disconnect {
+		disconnect(w)
+		return
+	}
+	writeFailure(w, f)
+}
+
+// nextFailure takes the failure due for a request type. Lock must be held.
+func (s *Server) nextFailure(reqType string) (Failure, bool) {
+	queue := s.failures[reqType]
+	if len(queue) == 0 {
+		return Failure{}, false
+	}
+	f := queue[0]
+	if f.Times > 0 {
+		queue[0].Times--
+		if queue[0].Times == 0 {
+			s.failures[reqType] = queue[1:]
+		}
+	}
+	return f, true
+}
+
+// decode reads the request data and checks its X_KEY
+func (s *Server) decode(raw json.RawMessage, v any, xKey *string) *Failure {
+	if json.Unmarshal(raw, v) != nil {
+		return &ErrBadRequest
+	}
+	if *xKey != s.XKey {
+		return &ErrUnauthorized
+	}
+	return nil
+}
+
+func (s *Server) getJWT(token string) (any, *Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.tokens[strings.TrimSpace(token)]
+	if !ok {
+		return nil, &ErrInvalidToken
+	}
+	return map[string]any{"profile": profile(s.users[id])}, nil
+}
+
+func (s *Server) getUser(id int) (any, *Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return nil, &ErrUserNotFound
+	}
+	return map[string]any{"profile": profile(u)}, nil
+}
+
+// addTransaction moves the balance, every request is applied like UM does
+func (s *Server) addTransaction(d utils.UMTransactionData) (any, *Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[d.UserID]
+	if !ok {
+		return nil, &ErrUserNotFound
+	}
+	if d.Amount < 0 {
+		return nil, &ErrBadRequest
+	}
+	balance := u.Balance + d.Amount
+	if DebitTypes[d.Type] {
+		if u.Balance < d.Amount {
+			f := ErrInsufficient
+			f.Data = map[string]any{"cost": d.Amount, "balance": u.Balance}
+			return nil, &f
+		}
+		balance = u.Balance - d.Amount
+	}
+	u.Balance = utils.RoundToTwoDigits(balance)
+
+	tx := Transaction{UMTransactionData: d, Balance: u.Balance}
+	s.txs = append(s.txs, tx)
+	return map[string]any{"balance": u.Balance}, nil
+}
+
+// addXp changes the XP, a negative amount reverts an earlier grant (assumed, see Server)
+func (s *Server) addXp(d utils.UMXpData) (any, *Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[d.UserID]
+	if !ok {
+		return nil, &ErrUserNotFound
+	}
+	u.XP += d.Amount
+	s.xp = append(s.xp, Xp{UMXpData: d, XP: u.XP})
+	return map[string]any{"xp": u.XP}, nil
+}
+
+func profile(u *User) map[string]any {
+	return map[string]any{
+		"id":           u.ID,
+		"display_name": u.DisplayName,
+		"xp":           u.XP,
+		"balance":      fmt.Sprintf("%.2f", u.Balance),
+	}
+}
+
+func writeFailure(w http.ResponseWriter, f Failure) {
+	res := map[string]any{"status": 0, "error": f.Code, "type": f.Type}
+	if f.Data != nil {
+		res["data"] = f.Data
+	}
+	writeJSON(w, res)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// disconnect closes the connection without an answer, the client sees a failed request
+func disconnect(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	_ = conn.Close()
+}
@@ -3,7 +3,6 @@ package grpcclient
 import (
 	"context"
 	"log"
-	"os"
 	"time"
 
 	pb "github.com/Milad-Abooali/4in-cs2skin-g2/src/proto"
@@ -39,25 +38,12 @@ func Connect(address string) {
 	log.Println("✅ Connected to gRPC Core:", address)
 }
 
-// SendQuery sends a raw SQL query to the Core with access token
-func SendQuery(query string) (*pb.QueryResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	token := os.Getenv("CORE_GRPC_TOKEN")
-
-	return client.Query(ctx, &pb.QueryRequest{
-		Token: token,
-		Query: query,
-	})
-}
-
 // TestConnection performs a simple test query to verify gRPC connectivity
 func TestConnection() {
-	resp, err := SendQuery("SELECT version();")
+	rows, err := Select("version() AS version").Rows()
 	if err != nil {
 		log.Printf("❌ gRPC test failed: %v", err)
 		return
 	}
-	log.Printf("✅ gRPC test successful: %v", resp)
+	log.Printf("✅ gRPC test successful: %v", rows)
 }
@@ -0,0 +1,555 @@
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/Milad-Abooali/4in-cs2skin-g2/src/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Values are never spliced into SQL. Statements carry ? placeholders and the values are
+// bound by Core through the Exec, QueryParams and Transaction RPCs. Table and column names are checked
+// against identRe, conditions and expressions are code constants.
+
+// DateTimeLayout is how time.Time values are bound
+const DateTimeLayout = "2006-01-02 15:04:05"
+
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Statement is SQL with ? placeholders and the values bound to them, in order
+type Statement struct {
+	SQL    string
+	Params []*pb.Param
+}
+
+// Result is what Core reports for an Exec
+type Result struct {
+	InsertedID   int64
+	RowsAffected int64
+}
+
+// Row is one selected row by column name
+type Row map[string]*structpb.Value
+
+// Number reads a numeric column, Core may send it as a number or a string
+func (r Row) Number(col string) float64 {
+	v := r[col]
+	if s, ok := v.GetKind().(*structpb.Value_StringValue); ok {
+		n, _ := strconv.ParseFloat(s.StringValue, 64)
+		return n
+	}
+	return v.GetNumberValue()
+}
+
+// Int reads an integer column like an ID
+func (r Row) Int(col string) int64 {
+	return int64(r.Number(col))
+}
+
+// String reads a text column
+func (r Row) String(col string) string {
+	return r[col].GetStringValue()
+}
+
+// Null tells whether a column is NULL or missing, like an aggregate over no rows
+func (r Row) Null(col string) bool {
+	v, ok := r[col]
+	if !ok || v == nil || v.GetKind() == nil {
+		return true
+	}
+	_, isNull := v.GetKind().(*structpb.Value_NullValue)
+	return isNull
+}
+
+// expr is a piece of SQL with its own placeholders
+type expr struct {
+	sql  string
+	args []any
+}
+
+// builder collects the errors and bound values shared by the query builders
+type builder struct {
+	err error
+}
+
+func (b *builder) ident(name string) string {
+	if !identRe.MatchString(name) && b.err == nil {
+		b.err = fmt.Errorf("invalid identifier %q", name)
+	}
+	return name
+}
+
+// statement binds the args of exprs in order and checks the placeholders match
+func (b *builder) statement(sql string, args []any) (Statement, error) {
+	if b.err != nil {
+		return Statement{}, b.err
+	}
+	st := Statement{SQL: sql}
+	for _, a := range args {
+		p, err := Bind(a)
+		if err != nil {
+			return Statement{}, err
+		}
+		st.Params = append(st.Params, p)
+	}
+	if n := strings.Count(sql, "?"); n != len(st.Params) {
+		return Statement{}, fmt.Errorf("%d placeholders for %d values in %q", n, len(st.Params), sql)
+	}
+	return st, nil
+}
+
+// Bind converts a Go value to a bound parameter, nil binds NULL
+func Bind(v any) (*pb.Param, error) {
+	switch t := v.(type) {
+	case nil:
+		return &pb.Param{}, nil
+	case int:
+		return &pb.Param{Value: &pb.Param_Int{Int: int64(t)}}, nil
+	case int32:
+		return &pb.Param{Value: &pb.Param_Int{Int: int64(t)}}, nil
+	case int64:
+		return &pb.Param{Value: &pb.Param_Int{Int: t}}, nil
+	case float32:
+		return &pb.Param{Value: &pb.Param_Float{Float: float64(t)}}, nil
+	case float64:
+		return &pb.Param{Value: &pb.Param_Float{Float: t}}, nil
+	case string:
+		return &pb.Param{Value: &pb.Param_Text{Text: t}}, nil
+	case []byte:
+		return &pb.Param{Value: &pb.Param_Text{Text: string(t)}}, nil
+	case bool:
+		return &pb.Param{Value: &pb.Param_Bool{Bool: t}}, nil
+	case time.Time:
+		return &pb.Param{Value: &pb.Param_Text{Text: t.UTC().Format(DateTimeLayout)}}, nil
+	}
+	return nil, fmt.Errorf("cannot bind %T", v)
+}
+
+// where renders ANDed conditions
+func where(conds []expr) (string, []any) {
+	if len(conds) == 0 {
+		return "", nil
+	}
+	parts := make([]string, len(conds))
+	var args []any
+	for i, c := range conds {
+		parts[i] = "(" + c.sql + ")"
+		args = append(args, c.args...)
+	}
+	return " WHERE " + strings.Join(parts, " AND "), args
+}
+
+/* Select */
+
+// SelectQuery builds a SELECT
+type SelectQuery struct {
+	builder
+	columns []string
+	from    string
+	fromSub *SelectQuery
+	alias   string
+	joins   []expr
+	where   []expr
+	orderBy string
+	limit   int
+}
+
+// Select starts a SELECT of columns, they may be expressions like "MAX(id) AS id"
+func Select(columns ...string) *SelectQuery {
+	return &SelectQuery{columns: columns}
+}
+
+// From sets the table
+func (q *SelectQuery) From(table string) *SelectQuery {
+	q.from = q.ident(table)
+	return q
+}
+
+// FromAs sets the table with an alias
+func (q *SelectQuery) FromAs(table, alias string) *SelectQuery {
+	q.from = q.ident(table)
+	q.alias = q.ident(alias)
+	return q
+}
+
+// FromSub selects from a subquery
+func (q *SelectQuery) FromSub(sub *SelectQuery, alias string) *SelectQuery {
+	q.fromSub = sub
+	q.alias = q.ident(alias)
+	return q
+}
+
+// Join adds an inner join on a condition
+func (q *SelectQuery) Join(table, alias, on string) *SelectQuery {
+	q.joins = append(q.joins, expr{sql: fmt.Sprintf(" JOIN %s %s ON %s", q.ident(table), q.ident(alias), on)})
+	return q
+}
+
+// Where adds a condition, conditions are ANDed
+func (q *SelectQuery) Where(cond string, args ...any) *SelectQuery {
+	q.where = append(q.where, expr{sql: cond, args: args})
+	return q
+}
+
+// OrderBy sets the ORDER BY expression
+func (q *SelectQuery) OrderBy(order string) *SelectQuery {
+	q.orderBy = order
+	return q
+}
+
+// Limit caps the rows
+func (q *SelectQuery) Limit(n int) *SelectQuery {
+	q.limit = n
+	return q
+}
+
+func (q *SelectQuery) render() (string, []any, error) {
+	var (
+		sb   strings.Builder
+		args []any
+	)
+	sb.WriteString("SELECT ")
+	sb.WriteString(strings.Join(q.columns, ", "))
+	switch {
+	case q.fromSub != nil:
+		sql, subArgs, err := q.fromSub.render()
+		if err != nil {
+			return "", nil, err
+		}
+		sb.WriteString(" FROM (" + sql + ") " + q.alias)
+		args = append(args, subArgs...)
+	case q.from != "":
+		sb.WriteString(" FROM " + q.from)
+		if q.alias != "" {
+			sb.WriteString(" " + q.alias)
+		}
+	}
+	for _, j := range q.joins {
+		sb.WriteString(j.sql)
+	}
+	w, wArgs := where(q.where)
+	sb.WriteString(w)
+	args = append(args, wArgs...)
+	if q.orderBy != "" {
+		sb.WriteString(" ORDER BY " + q.orderBy)
+	}
+	if q.limit > 0 {
+		sb.WriteString(" LIMIT ?")
+		args = append(args, q.limit)
+	}
+	return sb.String(), args, q.err
+}
+
+// Build returns the statement
+func (q *SelectQuery) Build() (Statement, error) {
+	sql, args, err := q.render()
+	if err != nil {
+		return Statement{}, err
+	}
+	return q.statement(sql, args)
+}
+
+// Rows runs the SELECT
+func (q *SelectQuery) Rows() ([]Row, error) {
+	st, err := q.Build()
+	if err != nil {
+		return nil, err
+	}
+	return Query(st)
+}
+
+/* Insert */
+
+// InsertQuery builds an INSERT of one row
+type InsertQuery struct {
+	builder
+	table    string
+	columns  []string
+	values   []expr
+	onDupKey *expr
+}
+
+// Insert starts an INSERT into table
+func Insert(table string) *InsertQuery {
+	q := &InsertQuery{}
+	q.table = q.ident(table)
+	return q
+}
+
+// Value binds a column value
+func (q *InsertQuery) Value(column string, v any) *InsertQuery {
+	return q.Expr(column, "?", v)
+}
+
+// Expr sets a column to an SQL expression like NOW()
+func (q *InsertQuery) Expr(column, sql string, args ...any) *InsertQuery {
+	q.columns = append(q.columns, q.ident(column))
+	q.values = append(q.values, expr{sql: sql, args: args})
+	return q
+}
+
+// OnDuplicateKeyUpdate adds the update of a row that already holds a unique key
+func (q *InsertQuery) OnDuplicateKeyUpdate(sql string, args ...any) *InsertQuery {
+	q.onDupKey = &expr{sql: sql, args: args}
+	return q
+}
+
+// Build returns the statement
+func (q *InsertQuery) Build() (Statement, error) {
+	values := make([]string, len(q.values))
+	var args []any
+	for i, v := range q.values {
+		values[i] = v.sql
+		args = append(args, v.args...)
+	}
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", q.table, strings.Join(q.columns, ", "), strings.Join(values, ", "))
+	if q.onDupKey != nil {
+		sql += " ON DUPLICATE KEY UPDATE " + q.onDupKey.sql
+		args = append(args, q.onDupKey.args...)
+	}
+	return q.statement(sql, args)
+}
+
+// Exec runs the INSERT
+func (q *InsertQuery) Exec() (Result, error) {
+	st, err := q.Build()
+	if err != nil {
+		return Result{}, err
+	}
+	return Exec(st)
+}
+
+/* Update */
+
+// UpdateQuery builds an UPDATE, it needs a condition
+type UpdateQuery struct {
+	builder
+	table string
+	sets  []expr
+	where []expr
+}
+
+// Update starts an UPDATE of table
+func Update(table string) *UpdateQuery {
+	q := &UpdateQuery{}
+	q.table = q.ident(table)
+	return q
+}
+
+// Set binds a column value
+func (q *UpdateQuery) Set(column string, v any) *UpdateQuery {
+	return q.SetExpr(column, "?", v)
+}
+
+// SetExpr sets a column to an SQL expression like attempts + 1
+func (q *UpdateQuery) SetExpr(column, sql string, args ...any) *UpdateQuery {
+	q.sets = append(q.sets, expr{sql: q.ident(column) + " = " + sql, args: args})
+	return q
+}
+
+// Where adds a condition, conditions are ANDed
+func (q *UpdateQuery) Where(cond string, args ...any) *UpdateQuery {
+	q.where = append(q.where, expr{sql: cond, args: args})
+	return q
+}
+
+// Build returns the statement
+func (q *UpdateQuery) Build() (Statement, error) {
+	if len(q.where) == 0 {
+		return Statement{}, fmt.Errorf("update of %s without condition", q.table)
+	}
+	sets := make([]string, len(q.sets))
+	var args []any
+	for i, s := range q.sets {
+		sets[i] = s.sql
+		args = append(args, s.args...)
+	}
+	w, wArgs := where(q.where)
+	return q.statement("UPDATE "+q.table+" SET "+strings.Join(sets, ", ")+w, append(args, wArgs...))
+}
+
+// Exec runs the UPDATE
+func (q *UpdateQuery) Exec() (Result, error) {
+	st, err := q.Build()
+	if err != nil {
+		return Result{}, err
+	}
+	return Exec(st)
+}
+
+/* Delete */
+
+// DeleteQuery builds a DELETE, it needs a condition
+type DeleteQuery struct {
+	builder
+	table string
+	where []expr
+}
+
+// Delete starts a DELETE from table
+func Delete(table string) *DeleteQuery {
+	q := &DeleteQuery{}
+	q.table = q.ident(table)
+	return q
+}
+
+// Where adds a condition, conditions are ANDed
+func (q *DeleteQuery) Where(cond string, args ...any) *DeleteQuery {
+	q.where = append(q.where, expr{sql: cond, args: args})
+	return q
+}
+
+// Build returns the statement
+func (q *DeleteQuery) Build() (Statement, error) {
+	if len(q.where) == 0 {
+		return Statement{}, fmt.Errorf("delete from %s without condition", q.table)
+	}
+	w, args := where(q.where)
+	return q.statement("DELETE FROM "+q.table+w, args)
+}
+
+// Exec runs the DELETE
+func (q *DeleteQuery) Exec() (Result, error) {
+	st, err := q.Build()
+	if err != nil {
+		return Result{}, err
+	}
+	return Exec(st)
+}
+
+/* Transaction */
+
+// Builder is a query that builds to a statement
+type Builder interface {
+	Build() (Statement, error)
+}
+
+// Tx is a batch of statements Core commits together or not at all.
+// Nothing is sent before Commit.
+type Tx struct {
+	statements []Statement
+	err        error
+}
+
+// Begin starts an empty batch
+func Begin() *Tx {
+	return &Tx{}
+}
+
+// Add appends a statement, a build error fails the Commit
+func (tx *Tx) Add(q Builder) *Tx {
+	st, err := q.Build()
+	if err != nil {
+		if tx.err == nil {
+			tx.err = err
+		}
+		return tx
+	}
+	tx.statements = append(tx.statements, st)
+	return tx
+}
+
+// Commit sends the batch, the results are in statement order
+func (tx *Tx) Commit() ([]Result, error) {
+	if tx.err != nil {
+		return nil, tx.err
+	}
+	if len(tx.statements) == 0 {
+		return nil, nil
+	}
+	return Transaction(tx.statements)
+}
+
+/* RPC */
+
+func paramsRequest(st Statement) *pb.ParamsRequest {
+	return &pb.ParamsRequest{
+		Token:  os.Getenv("CORE_GRPC_TOKEN"),
+		Query:  st.SQL,
+		Params: st.Params,
+	}
+}
+
+// responseError turns a failed Core response into an error
+func responseError(res *pb.QueryResponse, err error) error {
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		return fmt.Errorf("empty response")
+	}
+	if res.Status != "ok" {
+		return fmt.Errorf("core %s: %s", res.Status, res.Error)
+	}
+	return nil
+}
+
+// Exec sends a write statement to Core
+func Exec(st Statement) (Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := client.Exec(ctx, paramsRequest(st))
+	if err := responseError(res, err); err != nil {
+		return Result{}, err
+	}
+	return result(res.Data), nil
+}
+
+// result reads the inserted_id and rows_affected of a write
+func result(data *structpb.Struct) Result {
+	fields := data.GetFields()
+	return Result{
+		InsertedID:   int64(fields["inserted_id"].GetNumberValue()),
+		RowsAffected: int64(fields["rows_affected"].GetNumberValue()),
+	}
+}
+
+// Query sends a SELECT statement to Core
+func Query(st Statement) ([]Row, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := client.QueryParams(ctx, paramsRequest(st))
+	if err := responseError(res, err); err != nil {
+		return nil, err
+	}
+	values := res.Data.GetFields()["rows"].GetListValue().GetValues()
+	rows := make([]Row, 0, len(values))
+	for _, v := range values {
+		rows = append(rows, v.GetStructValue().GetFields())
+	}
+	return rows, nil
+}
+
+// Transaction sends statements Core runs in one database transaction
+func Transaction(statements []Statement) ([]Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req := &pb.TransactionRequest{Token: os.Getenv("CORE_GRPC_TOKEN")}
+	for _, st := range statements {
+		req.Statements = append(req.Statements, &pb.Statement{Query: st.SQL, Params: st.Params})
+	}
+	res, err := client.Transaction(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, fmt.Errorf("empty response")
+	}
+	if res.Status != "ok" {
+		return nil, fmt.Errorf("core %s: %s", res.Status, res.Error)
+	}
+	results := make([]Result, 0, len(res.Results))
+	for _, data := range res.Results {
+		results = append(results, result(data))
+	}
+	return results, nil
+}
@@ -0,0 +1,209 @@
+package grpcclient
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/Milad-Abooali/4in-cs2skin-g2/src/proto"
+)
+
+// paramValues reads the bound values of a statement back, nil is a NULL
+func paramValues(st Statement) []any {
+	var values []any
+	for _, p := range st.Params {
+		switch v := p.GetValue().(type) {
+		case nil:
+			values = append(values, nil)
+		case *pb.Param_Int:
+			values = append(values, v.Int)
+		case *pb.Param_Float:
+			values = append(values, v.Float)
+		case *pb.Param_Text:
+			values = append(values, v.Text)
+		case *pb.Param_Bool:
+			values = append(values, v.Bool)
+		}
+	}
+	return values
+}
+
+func TestIdentRe(t *testing.T) {
+	for _, name := range []string{"g2_bets", "_tmp", "Games2", "a"} {
+		if !identRe.MatchString(name) {
+			t.Errorf("%q rejected", name)
+		}
+	}
+	for _, name := range []string{"", "2games", "g2 bets", "g2_bets;", "g2_bets--", "g.id", "`g2_bets`", "g2-bets", "bét", "id=1"} {
+		if identRe.MatchString(name) {
+			t.Errorf("%q accepted", name)
+		}
+	}
+}
+
+func TestBuildersRejectBadIdentifiers(t *testing.T) {
+	for name, q := range map[string]Builder{
+		"select from":  Select("id").From("g2_bets; DROP TABLE g2_bets").Where("id = ?", 1),
+		"select alias": Select("id").FromAs("g2_bets", "b b"),
+		"select sub":   Select("id").FromSub(Select("id").From("g2 games"), "g"),
+		"join":         Select("id").From("g2_bets").Join("g2_games", "g;", "g.id = game_id"),
+		"insert table": Insert("g2-outbox").Value("id", 1),
+		"insert col":   Insert("g2_outbox").Value("status = 'done'", 1),
+		"update table": Update("1outbox").Set("status", "done").Where("id = ?", 1),
+		"update col":   Update("g2_outbox").Set("status, id", "done").Where("id = ?", 1),
+		"delete":       Delete("g2_outbox WHERE 1").Where("id = ?", 1),
+	} {
+		if st, err := q.Build(); err == nil {
+			t.Errorf("%s built %q", name, st.SQL)
+		}
+	}
+}
+
+func TestSelectBuild(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		q    *SelectQuery
+		sql  string
+		args []any
+	}{
+		{
+			"plain",
+			Select("id").From("g2_games"),
+			"SELECT id FROM g2_games",
+			nil,
+		},
+		{
+			"where order limit",
+			Select("id", "bet").From("g2_bets").
+				Where("user_id = ?", 7).
+				Where("state IN (?, ?)", "open", "won").
+				OrderBy("id DESC").
+				Limit(10),
+			"SELECT id, bet FROM g2_bets WHERE (user_id = ?) AND (state IN (?, ?)) ORDER BY id DESC LIMIT ?",
+			[]any{int64(7), "open", "won", int64(10)},
+		},
+		{
+			"join",
+			Select("b.id").FromAs("g2_bets", "b").
+				Join("g2_games", "g", "g.id = b.game_id").
+				Where("g.is_live = ?", true),
+			"SELECT b.id FROM g2_bets b JOIN g2_games g ON g.id = b.game_id WHERE (g.is_live = ?)",
+			[]any{true},
+		},
+		{
+			"subquery args come first",
+			Select("MAX(id) AS id").
+				FromSub(Select("id").From("g2_games").Where("room = ?", "main").Limit(5), "g").
+				Where("g.id > ?", int64(3)),
+			"SELECT MAX(id) AS id FROM (SELECT id FROM g2_games WHERE (room = ?) LIMIT ?) g WHERE (g.id > ?)",
+			[]any{"main", int64(5), int64(3)},
+		},
+	} {
+		st, err := tc.q.Build()
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if st.SQL != tc.sql {
+			t.Errorf("%s SQL\n got %s\nwant %s", tc.name, st.SQL, tc.sql)
+		}
+		if got := paramValues(st); !reflect.DeepEqual(got, tc.args) {
+			t.Errorf("%s args = %v, want %v", tc.name, got, tc.args)
+		}
+	}
+}
+
+func TestInsertBuild(t *testing.T) {
+	at := time.Date(2025, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*3600))
+	st, err := Insert("g2_outbox").
+		Value("idem_key", "main-1-2-win").
+		Expr("created_at", "NOW()").
+		Value("amount", 1.5).
+		Value("next_at", at).
+		Value("last_error", nil).
+		OnDuplicateKeyUpdate("id = LAST_INSERT_ID(id), attempts = ?", 0).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "INSERT INTO g2_outbox (idem_key, created_at, amount, next_at, last_error) VALUES (?, NOW(), ?, ?, ?)" +
+		" ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), attempts = ?"
+	if st.SQL != want {
+		t.Errorf("SQL\n got %s\nwant %s", st.SQL, want)
+	}
+	// Times are bound in UTC
+	args := []any{"main-1-2-win", 1.5, "2025-05-01 10:30:00", nil, int64(0)}
+	if got := paramValues(st); !reflect.DeepEqual(got, args) {
+		t.Errorf("args = %v, want %v", got, args)
+	}
+}
+
+func TestUpdateBuild(t *testing.T) {
+	st, err := Update("g2_outbox").
+		Set("status", "done").
+		SetExpr("attempts", "attempts + ?", 1).
+		Where("id = ?", int64(4)).
+		Where("status = ?", "sending").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "UPDATE g2_outbox SET status = ?, attempts = attempts + ? WHERE (id = ?) AND (status = ?)"
+	if st.SQL != want {
+		t.Errorf("SQL\n got %s\nwant %s", st.SQL, want)
+	}
+	args := []any{"done", int64(1), int64(4), "sending"}
+	if got := paramValues(st); !reflect.DeepEqual(got, args) {
+		t.Errorf("args = %v, want %v", got, args)
+	}
+
+	if _, err := Update("g2_outbox").Set("status", "done").Build(); err == nil {
+		t.Error("update without condition built")
+	}
+}
+
+func TestDeleteBuild(t *testing.T) {
+	st, err := Delete("g2_sagas").Where("bet_id = ?", 9).Where("step <> ?", "insert").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "DELETE FROM g2_sagas WHERE (bet_id = ?) AND (step <> ?)"; st.SQL != want {
+		t.Errorf("SQL\n got %s\nwant %s", st.SQL, want)
+	}
+	if got, args := paramValues(st), []any{int64(9), "insert"}; !reflect.DeepEqual(got, args) {
+		t.Errorf("args = %v, want %v", got, args)
+	}
+
+	if _, err := Delete("g2_sagas").Build(); err == nil {
+		t.Error("delete without condition built")
+	}
+}
+
+func TestBuildChecksValues(t *testing.T) {
+	if _, err := Select("id").From("g2_bets").Where("user_id = ? AND game_id = ?", 7).Build(); err == nil {
+		t.Error("built with a placeholder left unbound")
+	}
+	if _, err := Select("id").From("g2_bets").Where("user_id = ?", 7, 8).Build(); err == nil {
+		t.Error("built with an extra value")
+	}
+	if _, err := Update("g2_bets").Set("state", struct{}{}).Where("id = ?", 1).Build(); err == nil {
+		t.Error("built with a value that cannot be bound")
+	}
+}
+
+func TestTxKeepsTheFirstBuildError(t *testing.T) {
+	tx := Begin().
+		Add(Update("g2_bets").Set("state", "won").Where("id = ?", 1)).
+		Add(Update("g2_bets").Set("state", "lost")).
+		Add(Insert("bad table").Value("id", 1))
+	if len(tx.statements) != 1 || tx.statements[0].SQL != "UPDATE g2_bets SET state = ? WHERE (id = ?)" {
+		t.Errorf("statements = %+v", tx.statements)
+	}
+	// Nothing is sent, the client is not even connected
+	if _, err := tx.Commit(); err == nil || err.Error() != "update of g2_bets without condition" {
+		t.Errorf("commit = %v, want the update error", err)
+	}
+	if results, err := Begin().Commit(); results != nil || err != nil {
+		t.Errorf("empty commit = %v, %v", results, err)
+	}
+}
@@ -1,11 +1,10 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/apiapp"
-	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/events"
-	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/grpcclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/engine"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/validate"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
@@ -15,8 +14,40 @@ import (
 	"time"
 )
 
-var LiveBets map[int64][]models.Bet
-var BetsByMultiplier = make(map[int][]models.Bet) // int key (multiplier*100)
+// engineError maps engine errors to handler errors
+func engineError(err error) models.HandlerError {
+	var errR models.HandlerError
+	switch {
+	case errors.Is(err, engine.ErrGameStarted):
+		errR.Type = "GAME_STARTED"
+		errR.Code = 8001
+	case errors.Is(err, engine.ErrGameNotRunning):
+		errR.Type = "GAME_NOT_RUNNING"
+		errR.Code = 8002
+	case errors.Is(err, engine.ErrBetNotFound):
+		errR.Type = "BET_NOT_FOUND"
+		errR.Code = 8003
+	case errors.Is(err, engine.ErrBetCrashed):
+		errR.Type = "BET_ALREADY_CRASHED"
+		errR.Code = 8004
+	case errors.Is(err, engine.ErrBetPaid), errors.Is(err, engine.ErrBetSettling):
+		errR.Type = "BET_ALREADY_PAID"
+		errR.Code = 8005
+	case errors.Is(err, engine.ErrBetLimit):
+		errR.Type = "BET_LIMIT_REACHED"
+		errR.Code = 8006
+	case errors.Is(err, engine.ErrGameMaxBet):
+		errR.Type = "GAME_MAX_BET_REACHED"
+		errR.Code = 8007
+	case errors.Is(err, engine.ErrNoBets):
+		errR.Type = "NO_BETS_FOUND"
+		errR.Code = 8008
+	default:
+		errR.Type = "ENGINE_ERROR"
+		errR.Code = 8000
+	}
+	return errR
+}
 
 func AddBet(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 	var (
@@ -24,8 +55,31 @@ func AddBet(data map[string]interface{}) (models.HandlerOK, models.HandlerError)
 		resR models.HandlerOK
 	)
 
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return resR, rErr
+	}
+
+	// No new bets while the room drains for a shutdown or the scheduler is paused
+	if room.Draining() {
+		errR.Type = "MAINTENANCE"
+		errR.Code = 8016
+		return resR, errR
+	}
+	if sched := room.Scheduler(); sched.State != SchedulerRunning {
+		errR.Type = "GAME_PAUSED"
+		errR.Code = 8009
+		if sched.State == SchedulerMaintenance {
+			errR.Type = "MAINTENANCE"
+			errR.Code = 8016
+		}
+		errR.Data = sched
+		return resR, errR
+	}
+
 	// Check Live Game
-	if LiveGame.GameState != StateWaiting {
+	live := room.Engine.Live()
+	if live.GameState != StateWaiting {
 		errR.Type = "GAME_STARTED"
 		errR.Code = 8001
 		return resR, errR
@@ -73,38 +127,27 @@ func AddBet(data map[string]interface{}) (models.HandlerOK, models.HandlerError)
 		return resR, vErr
 	}
 
-	// Check Bet Limits per User
-	userBets := LiveBets[int64(userID)]
-
-	// User bets counts
-	if len(userBets) >= 10 {
-		errR.Type = "BET_LIMIT_REACHED"
-		errR.Code = 8006
-		return resR, errR
-	}
-
-	// User bets Amounts
-	var userTotal float64
-	for _, b := range userBets {
-		userTotal += b.Bet
-	}
-	if userTotal+bet > 200 {
-		errR.Type = "BET_LIMIT_REACHED"
-		errR.Code = 8006
-		return resR, errR
-	}
-
-	// Game Max Bet Amount
-	var allTotal float64
-	for _, bets := range LiveBets {
-		for _, b := range bets {
-			allTotal += b.Bet
+	// Client Seed (optional, falls back to the seed the player set, a bet without one adds no entropy)
+	clientSeed, _ := PlayerSeeds.Lookup(int64(userID))
+	if _, exists := data["clientSeed"]; exists {
+		clientSeed, vErr, ok = validate.RequireString(data, "clientSeed", false)
+		if !ok {
+			return resR, vErr
+		}
+		if len(clientSeed) > clientSeedMaxLen {
+			errR.Type = "INVALID_TYPE_OR_FORMAT"
+			errR.Code = 5003
+			errR.Data = map[string]any{
+				"fieldName": "clientSeed",
+				"fieldType": "string",
+			}
+			return resR, errR
 		}
 	}
-	if allTotal+bet > 10000 {
-		errR.Type = "GAME_MAX_BET_REACHED"
-		errR.Code = 8007
-		return resR, errR
+
+	// Bet limits of this round (published risk levers)
+	if err := room.Engine.CheckBet(int64(userID), bet); err != nil {
+		return resR, engineError(err)
 	}
 
 	// Check Balance
@@ -118,103 +161,97 @@ func AddBet(data map[string]interface{}) (models.HandlerOK, models.HandlerError)
 		return resR, errR
 	}
 
-	// Add Transaction
-	Transaction, err := utils.AddTransaction(
-		userID,
-		"game_loss",
-		strconv.FormatInt(LiveGame.ID, 10),
-		utils.RoundToTwoDigits(bet),
-		"",
-		"Crash",
-	)
+	// Creat Bet
+	newBet := models.Bet{
+		ID:          0,
+		Bet:         utils.RoundToTwoDigits(bet),
+		Room:        room.ID,
+		GameID:      live.ID,
+		UserID:      int64(userID),
+		Avatar:      avatar,
+		XP:          xp,
+		DisplayName: displayName,
+		Multiplier:  utils.RoundToTwoDigits(multiplier),
+		ClientSeed:  clientSeed,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	// From here the bet is placed as a saga, a failure after the debit refunds the stake
+	saga := room.newBetSaga(newBet)
+
+	// Insert to Database, the bet ID keys the debit
+	newID, err := room.Bets.Insert(newBet)
 	if err != nil {
-		return resR, models.HandlerError{}
+		saga.fail(SagaInsert, fmt.Errorf("insert: %v", err))
+		errR.Type = "DB_ERROR_GRPC"
+		errR.Code = 8000
+		return resR, errR
 	}
-	errCode, status, errType = utils.SafeExtractErrorStatus(Transaction)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
+
+	// Update Game ID
+	newBet.ID = newID
+	saga.bet.ID = newID
+	saga.ok(SagaInsert, "")
+
+	// Debit, recorded in the outbox first and sent inline
+	debit, err := room.enqueue(OutboxDebit, newBet, newBet.Bet)
+	if err != nil {
+		saga.fail(SagaDebit, err)
+		errR.Type = "DB_ERROR_GRPC"
+		errR.Code = 8000
+		return resR, errR
+	}
+	if err := deliverOutbox(debit); err != nil {
+		saga.debitUnknown = errors.Is(err, errOutboxUnknown)
+		saga.fail(SagaDebit, err)
+		var umErr *UMError
+		if !errors.As(err, &umErr) {
+			return resR, models.HandlerError{}
+		}
+		errR.Type = umErr.Type
+		errR.Code = umErr.Code
+		if umErr.Data != nil {
+			errR.Data = umErr.Data
 		}
 		return resR, errR
 	}
-
-	// HE
-	LiveGame.Tracker.AddIncome(bet)
+	saga.ok(SagaDebit, fmt.Sprintf("outbox %d", debit.ID))
 
 	// Add XP
+	saga.xp = int(0.6 * bet)
 	AddXp, err := utils.AddXp(
 		userID,
-		int(0.6*bet),
+		saga.xp,
 		"Add Bet",
 		"G2",
 	)
 	if err != nil {
+		saga.fail(SagaXp, err)
 		return resR, models.HandlerError{}
 	}
 	errCode, status, errType = utils.SafeExtractErrorStatus(AddXp)
 	if status != 1 {
+		saga.fail(SagaXp, fmt.Errorf("UM %s:%d", errType, errCode))
 		errR.Type = errType
 		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
+		if AddXp["data"] != nil {
+			errR.Data = AddXp["data"]
 		}
 		return resR, errR
 	}
+	saga.ok(SagaXp, strconv.Itoa(saga.xp))
 
-	// Creat Bet
-	newBet := models.Bet{
-		ID:          0,
-		Bet:         utils.RoundToTwoDigits(bet),
-		GameID:      LiveGame.ID,
-		UserID:      int64(userID),
-		Avatar:      avatar,
-		XP:          xp,
-		DisplayName: displayName,
-		Multiplier:  utils.RoundToTwoDigits(multiplier),
-		CreatedAt:   time.Now().UTC(),
-	}
-
-	// Insert to Database
-	betJSON, err := json.Marshal(newBet)
-	if err != nil {
-		log.Fatalln("failed to marshal game:", err)
-	}
-	// Sanitize and build query
-	query := fmt.Sprintf(
-		`INSERT INTO g2_bets (user_id, game_id, bet) 
-				VALUES (%d, %d, '%s')`,
-		newBet.UserID,
-		newBet.GameID,
-		string(betJSON),
-	)
-
-	// gRPC Call Insert User
-	res, err := grpcclient.SendQuery(query)
-	if err != nil || res == nil || res.Status != "ok" {
-		errR.Type = "DB_ERROR_GRPC"
-		errR.Code = 8000
-		return resR, errR
-	}
-	dataDB := res.Data.GetFields()
-	newID := int64(dataDB["inserted_id"].GetNumberValue())
-	if newID < 1 {
-		errR.Type = "DB_ERROR_RES"
-		errR.Code = 8000
-		return resR, errR
+	// Update Live Bets
+	if err := room.Engine.PlaceBet(newBet); err != nil {
+		saga.fail(SagaPlace, err)
+		return resR, engineError(err)
 	}
+	saga.ok(SagaPlace, "")
 
-	// Update Game ID
-	newBet.ID = newID
-
-	// Update Live Bets
-	LiveBets[int64(userID)] = append(LiveBets[int64(userID)], newBet)
-	events.Emit("all", "liveBets", LiveBets)
+	// HE
+	live.Tracker.AddIncome(bet)
 
-	// Update BetsByMultiplier
-	key := int(math.Round(newBet.Multiplier * 100)) // bucket with 2 decimals
-	BetsByMultiplier[key] = append(BetsByMultiplier[key], newBet)
+	room.Emit("liveBets", room.Engine.Bets())
 
 	// Success
 	resR.Type = "addBet"
@@ -228,15 +265,18 @@ func CheckoutBet(data map[string]interface{}) (models.HandlerOK, models.HandlerE
 		resR models.HandlerOK
 	)
 
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return resR, rErr
+	}
+
 	// Check Live Game
-	if LiveGame.GameState != StateRunning {
+	if room.Engine.Live().GameState != StateRunning {
 		errR.Type = "GAME_NOT_RUNNING"
 		errR.Code = 8002
 		return resR, errR
 	}
 
-	multiplier := LiveGame.Multiplier
-
 	// Check Token
 	userJWT, vErr, ok := validate.RequireString(data, "token", false)
 	if !ok {
@@ -258,7 +298,6 @@ func CheckoutBet(data map[string]interface{}) (models.HandlerOK, models.HandlerE
 	userData := resp["data"].(map[string]interface{})
 	profile := userData["profile"].(map[string]interface{})
 	userID := int(profile["id"].(float64))
-	displayName := profile["display_name"].(string)
 
 	// Check Bet
 	betID, vErr, ok := validate.RequireInt(data, "betID")
@@ -266,93 +305,23 @@ func CheckoutBet(data map[string]interface{}) (models.HandlerOK, models.HandlerE
 		return resR, vErr
 	}
 
-	// Get Bet
-	bet, ok := getBet(int64(userID), betID)
-	if !ok {
-		errR.Type = "BET_NOT_FOUND"
-		errR.Code = 8003
-		return resR, errR
-	}
-
-	if bet.Multiplier <= multiplier {
-		errR.Type = "BET_ALREADY_CRASHED"
-		errR.Code = 8004
-		return resR, errR
-	}
-
-	if bet.Payout > 0 {
-		errR.Type = "BET_ALREADY_PAID"
-		errR.Code = 8005
-		return resR, errR
-	}
-
-	// Win Price
-	winAmount := utils.RoundToTwoDigits(bet.Bet * multiplier)
-
-	// Add Transaction
-	Transaction, err := utils.AddTransaction(
-		userID,
-		"game_win",
-		"2",
-		winAmount,
-		strconv.FormatInt(bet.ID, 10),
-		"Crash",
-	)
-	if err != nil {
-		return resR, models.HandlerError{}
-	}
-	errCode, status, errType = utils.SafeExtractErrorStatus(Transaction)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
-		}
-		return resR, errR
-	}
-
-	// HE
-	LiveGame.Tracker.AddExpense(winAmount)
-
-	bet.Payout = winAmount
-	bet.CheckoutBy = "User"
-
-	// Update DB
-	betJSON, err := json.Marshal(bet)
+	// Claim Bet, from here it is settled
+	bet, multiplier, err := room.Engine.Cashout(int64(userID), betID)
 	if err != nil {
-		log.Fatalln("Failed to marshal bet:", err)
-	}
-	// Sanitize and build query
-	query := fmt.Sprintf(
-		`Update g2_bets SET bet = '%s' WHERE id = %d`,
-		string(betJSON),
-		bet.ID,
-	)
-	res, err := grpcclient.SendQuery(query)
-	if err != nil || res == nil || res.Status != "ok" {
-		log.Fatalln("GRPC_ERROR", err)
-	}
-	dataDB := res.Data.GetFields()
-	exist := dataDB["rows_affected"].GetNumberValue()
-	if exist == 0 {
-		log.Fatalln("NOT_UPDATED", dataDB)
+		return resR, engineError(err)
 	}
 
-	Leaderboard.Add(*bet)
-
-	events.Emit("all", "liveBets", LiveBets)
-
-	// Send Live Winner
-	go sendLiveWinner(
-		displayName,
-		strconv.FormatFloat(bet.Bet, 'f', 2, 64),
-		strconv.FormatFloat(bet.Multiplier, 'f', 2, 64),
-		strconv.FormatFloat(bet.Payout, 'f', 2, 64),
-	)
+	// Win Price
+	winAmount := room.Engine.Live().Limits.CapWin(utils.RoundToTwoDigits(bet.Bet * multiplier))
 
-	// Success
+	// Success, a credit that could not be recorded yet is pending
 	resR.Type = "checkOutBet"
 	resR.Data = nil
+	if !room.settlePayout(bet, winAmount, multiplier, "User") {
+		resR.Data = map[string]interface{}{
+			"pending": true,
+		}
+	}
 	return resR, errR
 }
 
@@ -362,12 +331,16 @@ func CheckoutAll(data map[string]interface{}) (models.HandlerOK, models.HandlerE
 		resR models.HandlerOK
 	)
 
-	if LiveGame.GameState != StateRunning {
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return resR, rErr
+	}
+
+	if room.Engine.Live().GameState != StateRunning {
 		errR.Type = "GAME_NOT_RUNNING"
 		errR.Code = 8002
 		return resR, errR
 	}
-	multiplier := LiveGame.Multiplier
 
 	// === JWT Check ===
 	userJWT, vErr, ok := validate.RequireString(data, "token", false)
@@ -391,168 +364,133 @@ func CheckoutAll(data map[string]interface{}) (models.HandlerOK, models.HandlerE
 	profile := userData["profile"].(map[string]interface{})
 	userID := int64(profile["id"].(float64))
 
-	bets, ok := LiveBets[userID]
-	if !ok || len(bets) == 0 {
-		errR.Type = "NO_BETS_FOUND"
-		errR.Code = 8008
-		return resR, errR
+	// Claim every open bet below its target, each one is then settled
+	bets, multiplier, err := room.Engine.CashoutAll(userID)
+	if err != nil {
+		return resR, engineError(err)
 	}
+	limits := room.Engine.Live().Limits
 
-	closed := 0
-	for i := range bets {
-		bet := &bets[i]
-
-		if bet.Payout > 0 || bet.Multiplier <= multiplier {
-			continue
-		}
-
-		winAmount := utils.RoundToTwoDigits(bet.Bet * multiplier)
-
-		Transaction, err := utils.AddTransaction(
-			int(userID),
-			"game_win",
-			"2",
-			winAmount,
-			strconv.FormatInt(bet.ID, 10),
-			"Crash",
-		)
-		if err != nil {
-			log.Println("CheckoutAll > AddTransaction error:", err)
-			continue
-		}
-		errCode, status, errType = utils.SafeExtractErrorStatus(Transaction)
-		if status != 1 {
-			log.Println("CheckoutAll > Transaction failed:", errType, errCode)
-			continue
-		}
-
-		// HE
-		LiveGame.Tracker.AddExpense(winAmount)
-
-		bet.Payout = winAmount
-		bet.CheckoutBy = "User"
-		bet.CheckoutOn = multiplier
-
-		betJSON, err := json.Marshal(bet)
-		if err != nil {
-			log.Println("CheckoutAll > Marshal error:", err)
-			continue
+	closed, pending := 0, 0
+	for _, bet := range bets {
+		winAmount := limits.CapWin(utils.RoundToTwoDigits(bet.Bet * multiplier))
+		if room.settlePayout(bet, winAmount, multiplier, "User") {
+			closed++
+		} else {
+			pending++
 		}
-		query := fmt.Sprintf(
-			`UPDATE g2_bets SET bet = '%s' WHERE id = %d`,
-			string(betJSON),
-			bet.ID,
-		)
-		res, err := grpcclient.SendQuery(query)
-		if err != nil || res == nil || res.Status != "ok" {
-			log.Println("CheckoutAll > GRPC update error:", err)
-			continue
-		}
-
-		Leaderboard.Add(*bet)
-		events.Emit("all", "liveBets", LiveBets)
-
-		go sendLiveWinner(
-			bet.DisplayName,
-			strconv.FormatFloat(bet.Bet, 'f', 2, 64),
-			strconv.FormatFloat(bet.Multiplier, 'f', 2, 64),
-			strconv.FormatFloat(bet.Payout, 'f', 2, 64),
-		)
-		closed++
 	}
 
 	resR.Type = "checkOutAll"
 	resR.Data = map[string]interface{}{
-		"closed_count": closed,
+		"closed_count":  closed,
+		"pending_count": pending,
 	}
 	return resR, errR
 }
 
-func GetLiveBets(_ map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+func GetLiveBets(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 	var (
 		errR models.HandlerError
 		resR models.HandlerOK
 	)
 
-	events.Emit("all", "liveBets", LiveBets)
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return resR, rErr
+	}
+
+	room.Emit("liveBets", room.Engine.Bets())
 
 	// Success
 	resR.Type = "getLiveBets"
 	return resR, errR
 }
 
-func processStep(multiplier float64) {
-	for _, bets := range LiveBets {
-		for _, bet := range bets {
-			if bet.Payout == 0 && multiplier >= bet.Multiplier {
-				payout := utils.RoundToTwoDigits(bet.Bet * multiplier)
-				sendPayout(bet.UserID, bet.ID, payout)
+func (r *Room) processStep(due []models.Bet, multiplier float64) {
+	limits := r.Engine.Live().Limits
+	for _, bet := range due {
+		// A tick may jump past the target, the bet is paid at its target
+		on := math.Min(bet.Multiplier, multiplier)
+		payout := limits.CapWin(utils.RoundToTwoDigits(bet.Bet * on))
+		r.settlePayout(bet, payout, on, "Multiplier")
+	}
+}
+
+// Backoff of a credit that could not be recorded, about 25s over every attempt. A var so
+// tests can shorten it.
+var (
+	payoutRetryMin = 250 * time.Millisecond
+	payoutRetryMax = 10 * time.Second
+	payoutAttempts = 8
+)
+
+// settlePayout credits a bet the engine moved to settling. When the credit cannot be
+// recorded the bet stays settling at the multiplier it earned and the credit is retried
+// in the background, endGame waits for it. It returns false in that case.
+// After payoutAttempts the bet is stored unpaid, the reconciliation reports the missing
+// credit so the round can finish while Core is down.
+func (r *Room) settlePayout(bet models.Bet, payout, multiplier float64, by string) bool {
+	err := r.sendPayout(bet, payout, multiplier, by)
+	if err == nil {
+		return true
+	}
+	log.Printf("settlePayout > room %s bet %d: %v, retrying", r.ID, bet.ID, err)
+	go func() {
+		wait := payoutRetryMin
+		for attempt := 2; attempt <= payoutAttempts; attempt++ {
+			time.Sleep(wait)
+			err := r.sendPayout(bet, payout, multiplier, by)
+			if err == nil {
+				return
 			}
+			wait = min(wait*2, payoutRetryMax)
+			log.Printf("settlePayout > room %s bet %d attempt %d: %v, next in %s", r.ID, bet.ID, attempt, err, wait)
 		}
-	}
+		r.abandonPayout(bet, payout, multiplier, by)
+	}()
+	return false
 }
 
-func sendPayout(userID int64, betID int64, payout float64) bool {
-	// Get Bet by ID (returns pointer)
-	bet, ok := getBet(userID, betID)
+// abandonPayout stores a bet whose credit could not be recorded as unpaid
+func (r *Room) abandonPayout(bet models.Bet, payout, multiplier float64, by string) {
+	unpaid, ok := r.Engine.Abandon(bet.UserID, bet.ID, payout, by, multiplier)
 	if !ok {
-		return false
-	}
-
-	if bet.Payout > 0 {
-		return false
+		return
 	}
+	log.Printf("settlePayout > room %s bet %d: credit %.2f at %.2fx not recorded after %d attempts, left to reconciliation",
+		r.ID, bet.ID, payout, multiplier, payoutAttempts)
+	r.storeBet(unpaid)
+	r.Emit("liveBets", r.Engine.Bets())
+}
 
-	// Add Transaction
-	Transaction, err := utils.AddTransaction(
-		int(userID),
-		"game_win",
-		"2",
-		payout,
-		strconv.FormatInt(bet.ID, 10),
-		"Crash",
-	)
+// sendPayout records the credit of a settling bet and settles it. An error means the
+// credit is not recorded and the bet is still settling.
+func (r *Room) sendPayout(bet models.Bet, payout, multiplier float64, by string) error {
+	// Record the credit, the outbox delivers it even when UM is down. The key is per bet,
+	// so a retry never adds a second credit.
+	credit, err := r.enqueue(OutboxWin, bet, payout)
 	if err != nil {
-		return false
-	}
-	_, status, _ := utils.SafeExtractErrorStatus(Transaction)
-	if status != 1 {
-		return false
+		return err
 	}
 
-	// HE
-	LiveGame.Tracker.AddExpense(payout)
+	// Update bet in the engine
+	settled, ok := r.Engine.Settle(bet.UserID, bet.ID, payout, by, multiplier)
+	if !ok {
+		log.Printf("sendPayout > bet %d paid but not settling", bet.ID)
+		return nil
+	}
+	bet = settled
 
-	// Update bet in memory (no need to reassign slice element)
-	bet.Payout = payout
-	bet.CheckoutBy = "Multiplier"
+	// HE, counted once the bet is settled
+	r.Engine.Live().Tracker.AddExpense(payout)
 
 	// Update DB
-	betJSON, err := json.Marshal(bet)
-	if err != nil {
-		log.Fatalln("Failed to marshal bet:", err)
-		return false
-	}
-
-	query := fmt.Sprintf(
-		`UPDATE g2_bets SET bet = '%s' WHERE id = %d`,
-		string(betJSON),
-		bet.ID,
-	)
-	res, err := grpcclient.SendQuery(query)
-	if err != nil || res == nil || res.Status != "ok" {
-		log.Fatalln("GRPC_ERROR", err)
-		return false
-	}
-	dataDB := res.Data.GetFields()
-	exist := dataDB["rows_affected"].GetNumberValue()
-	if exist == 0 {
-		log.Fatalln("NOT_UPDATED", dataDB)
-		return false
-	}
+	r.storeBet(bet)
+	sendOutbox(credit)
 
-	Leaderboard.Add(*bet)
-	events.Emit("all", "liveBets", LiveBets)
+	r.Leaderboard.Add(bet)
+	r.Emit("liveBets", r.Engine.Bets())
 
 	// Send Live Winner
 	go sendLiveWinner(
@@ -562,20 +500,25 @@ func sendPayout(userID int64, betID int64, payout float64) bool {
 		strconv.FormatFloat(bet.Payout, 'f', 2, 64),
 	)
 
-	return true
+	return nil
 }
 
-func getBet(userID, betID int64) (*models.Bet, bool) {
-	bets, ok := LiveBets[userID]
-	if !ok {
-		return nil, false
+// storeBet updates the bet row. A failure is logged only, the outbox holds the credit
+// and the reconciliation reports the row.
+func (r *Room) storeBet(bet models.Bet) bool {
+	if err := r.Bets.Update(bet); err != nil {
+		log.Println("storeBet > GRPC_ERROR bet", bet.ID, err)
+		return false
 	}
-	for i := range bets {
-		if bets[i].ID == betID {
-			return &bets[i], true
-		}
+	return true
+}
+
+// deleteBet removes the row of a bet whose stake was not debited
+func (r *Room) deleteBet(betID int64) {
+	if err := r.Bets.Delete(betID); err != nil {
+		// Recovery voids it, its debit entry is missing or not done
+		log.Println("deleteBet > GRPC_ERROR bet", betID, err)
 	}
-	return nil, false
 }
 
 func sendLiveWinner(displayName string, bet string, multiplier string, payout string) bool {
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
+)
+
+// requireAdmin checks the adminKey field of an admin request
+func requireAdmin(data map[string]interface{}) (models.HandlerError, bool) {
+	var errR models.HandlerError
+
+	if _, err := utils.ValidateAdminKey(data); err != nil {
+		errR.Type = strings.Split(err.Error(), ":")[0]
+		errR.Code = 2001
+		return errR, false
+	}
+	return errR, true
+}
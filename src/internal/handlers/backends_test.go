@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/fakecore"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/fakeum"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/grpcclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+)
+
+// startBackends points G2 at a fresh fake Core and fake UM and resets the rooms to the
+// default one on them
+func startBackends(t *testing.T) (*fakecore.Server, *fakeum.Server) {
+	t.Helper()
+	core, err := fakecore.Start("127.0.0.1:0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(core.Stop)
+	t.Setenv("CORE_GRPC_TOKEN", "")
+	grpcclient.Connect(core.Addr())
+
+	um := fakeum.Start()
+	t.Cleanup(um.Close)
+	t.Setenv("API_UM", um.Env())
+
+	setRooms([]RoomConfig{defaultRoomConfig()})
+	return core, um
+}
+
+// storedBet inserts a bet of a stored game
+func storedBet(t *testing.T, r *Room, bet models.Bet) models.Bet {
+	t.Helper()
+	id, err := r.Bets.Insert(bet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bet.ID = id
+	return bet
+}
+
+// outboxStatus loads the status of an entry
+func outboxStatus(t *testing.T, key string) string {
+	t.Helper()
+	e, found, err := outbox.Find(key)
+	if err != nil || !found {
+		t.Fatalf("outbox %s: found %v, %v", key, found, err)
+	}
+	return e.Status
+}
+
+// balance reads the UM balance of a user
+func balance(t *testing.T, um *fakeum.Server, userID int) float64 {
+	t.Helper()
+	u, ok := um.User(userID)
+	if !ok {
+		t.Fatalf("user %d not found", userID)
+	}
+	return u.Balance
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"sync"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/provablyfair"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/validate"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
+)
+
+// RoundClientSeedBets is the number of players whose client seeds build the round client seed
+const RoundClientSeedBets = 5
+
+// clientSeedMaxLen caps the client seed a player can set
+const clientSeedMaxLen = 64
+
+// ClientSeeds keeps the client seed each player chose
+type ClientSeeds struct {
+	data map[int64]string
+	mu   sync.Mutex
+}
+
+// PlayerSeeds Global instance
+var PlayerSeeds = &ClientSeeds{data: make(map[int64]string)}
+
+// Lookup returns the client seed the player set, the server never makes one up for them
+func (c *ClientSeeds) Lookup(userID int64) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seed, ok := c.data[userID]
+	return seed, ok
+}
+
+// Set replaces the player's client seed
+func (c *ClientSeeds) Set(userID int64, seed string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[userID] = seed
+}
+
+// roundClientSeed combines the seeds players submitted on the first bets, one seed per
+// player and at most RoundClientSeedBets of them. Bets must be ordered by ID, bets
+// without a submitted seed do not count.
+func roundClientSeed(bets []models.Bet) string {
+	seeds := make([]string, 0, RoundClientSeedBets)
+	seen := make(map[int64]bool, RoundClientSeedBets)
+	for _, b := range bets {
+		if len(seeds) == RoundClientSeedBets {
+			break
+		}
+		if b.ClientSeed == "" || seen[b.UserID] {
+			continue
+		}
+		seen[b.UserID] = true
+		seeds = append(seeds, b.ClientSeed)
+	}
+	return provablyfair.CombineClientSeeds(seeds)
+}
+
+// roundSeed is a server seed drawn from the configured source
+type roundSeed struct {
+	seed       string
+	hash       string
+	chainHash  string
+	chainIndex int
+}
+
+// drawRoundSeed draws the next server seed
+func drawRoundSeed() *roundSeed {
+	seed, hash, chainHash, chainIndex := nextServerSeed()
+	return &roundSeed{seed: seed, hash: hash, chainHash: chainHash, chainIndex: chainIndex}
+}
+
+// fixNextClientSeed sets the client seed of the next round from the bets of this one.
+// The next server seed was drawn and its hash published when this round opened.
+func (r *Room) fixNextClientSeed(bets []models.Bet) {
+	r.seedMu.Lock()
+	defer r.seedMu.Unlock()
+	r.nextClientSeed = roundClientSeed(bets)
+}
+
+// takeRoundSeeds returns the seeds of the round being opened and draws the server seed of
+// the round after it. The hash of that seed is published while this round takes bets, so
+// the client seed those bets fix can never be chosen by the server after it.
+// The first round of a room has no client seed, its server seed is drawn on the spot.
+func (r *Room) takeRoundSeeds() (server *roundSeed, clientSeed string, next *roundSeed) {
+	r.seedMu.Lock()
+	defer r.seedMu.Unlock()
+	server, clientSeed = r.nextSeed, r.nextClientSeed
+	if server == nil {
+		server, clientSeed = drawRoundSeed(), ""
+	}
+	r.nextSeed, r.nextClientSeed = drawRoundSeed(), ""
+	return server, clientSeed, r.nextSeed
+}
+
+// SetClientSeed API handler for clients
+func SetClientSeed(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	// Check Token
+	userJWT, vErr, ok := validate.RequireString(data, "token", false)
+	if !ok {
+		return resR, vErr
+	}
+	resp, err := utils.VerifyJWT(userJWT)
+	if err != nil {
+		return resR, models.HandlerError{}
+	}
+	errCode, status, errType := utils.SafeExtractErrorStatus(resp)
+	if status != 1 {
+		errR.Type = errType
+		errR.Code = errCode
+		if resp["data"] != nil {
+			errR.Data = resp["data"]
+		}
+		return resR, errR
+	}
+	userData := resp["data"].(map[string]interface{})
+	profile := userData["profile"].(map[string]interface{})
+	userID := int64(profile["id"].(float64))
+
+	// Check Seed
+	clientSeed, vErr, ok := validate.RequireString(data, "clientSeed", false)
+	if !ok {
+		return resR, vErr
+	}
+	if len(clientSeed) > clientSeedMaxLen {
+		errR.Type = "INVALID_TYPE_OR_FORMAT"
+		errR.Code = 5003
+		errR.Data = map[string]any{
+			"fieldName": "clientSeed",
+			"fieldType": "string",
+		}
+		return resR, errR
+	}
+
+	PlayerSeeds.Set(userID, clientSeed)
+
+	// Success
+	resR.Type = "setClientSeed"
+	resR.Data = map[string]interface{}{
+		"clientSeed": clientSeed,
+	}
+	return resR, errR
+}
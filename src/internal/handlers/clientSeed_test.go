@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+)
+
+func TestServerSeedIsCommittedBeforeClientSeed(t *testing.T) {
+	r := &Room{}
+	first, clientSeed, next := r.takeRoundSeeds()
+	if clientSeed != "" {
+		t.Errorf("first round client seed = %q, want none", clientSeed)
+	}
+	if next.hash == first.hash {
+		t.Fatal("next server seed is the current one")
+	}
+
+	// Bets of the first round were placed while next.hash was published
+	bets := []models.Bet{{ID: 1, UserID: 7, ClientSeed: "lucky"}, {ID: 2, UserID: 8, ClientSeed: "seven"}}
+	r.fixNextClientSeed(bets)
+
+	second, clientSeed, _ := r.takeRoundSeeds()
+	if second != next {
+		t.Errorf("second round seed %s, want the one committed as %s", second.hash, next.hash)
+	}
+	if want := roundClientSeed(bets); clientSeed != want {
+		t.Errorf("second round client seed = %q, want %q", clientSeed, want)
+	}
+}
@@ -1,257 +1,201 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
-	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/events"
-	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/grpcclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/engine"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/he"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/provablyfair"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/risk"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
 	"log"
 	"time"
 )
 
 const (
-	StateWaiting  = 0
-	StateRunning  = 1
-	StateCrashed  = 2
-	StateFinished = 4
+	StateWaiting  = engine.StateWaiting
+	StateRunning  = engine.StateRunning
+	StateCrashed  = engine.StateCrashed
+	StateFinished = engine.StateFinished
 )
 
-var LiveGame *models.LiveGame
-
-func GetLiveGame(_ map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+func GetLiveGame(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 	var (
 		errR models.HandlerError
 		resR models.HandlerOK
 	)
 
-	events.Emit("all", "liveGame", LiveGame)
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return resR, rErr
+	}
+	room.Emit("liveGame", room.Engine.Live())
 
 	// Success
 	resR.Type = "getLiveGame"
 	return resR, errR
 }
 
-func NextGame(id int64) {
-	serverSeed, serverSeedHash := provablyfair.GenerateServerSeed()
+func (r *Room) NextGame(id int64) {
+	// Timings changed by admins apply from this round on
+	timings := r.roundTimings()
 
-	HE, _ := he.GetAvgHE("g1_games", 30)
-
-	var crashAt float64
-	if HE > 8 || HE == 0 {
-		crashAt = utils.RoundToTwoDigits(provablyfair.CalculateCrashMultiplier(serverSeed))
-	}
-	if HE > 0 && HE < 8 {
-		for {
-			crashAt = utils.RoundToTwoDigits(provablyfair.CalculateCrashMultiplier(serverSeed))
-			if crashAt < 5 {
-				break
-			}
-		}
-	}
-	if HE < 0 {
-		for {
-			crashAt = utils.RoundToTwoDigits(provablyfair.CalculateCrashMultiplier(serverSeed))
-			if crashAt < 3 {
-				break
-			}
-		}
-	}
+	// The server seed hash was published with the round before, whose bets then fixed the client seed
+	seed, clientSeed, next := r.takeRoundSeeds()
 
 	newGame := models.Game{
 		ID:             id,
+		Room:           r.ID,
 		StartAt:        time.Now().UTC(),
 		Multiplier:     0.00,
-		CrashAt:        utils.RoundToTwoDigits(crashAt),
-		ServerSeedHash: serverSeedHash,
-		ServerSeed:     serverSeed,
+		ServerSeedHash: seed.hash,
+		ServerSeed:     seed.seed,
+		ClientSeed:     clientSeed,
+		SeedChainHash:  seed.chainHash,
+		SeedChainIndex: seed.chainIndex,
 	}
 
 	// Insert to Database
-	gameJSON, err := json.Marshal(newGame)
+	newID, err := r.Games.Insert(newGame)
 	if err != nil {
-		log.Fatalln("failed to marshal game:", err)
-	}
-	// Sanitize and build query
-	query := fmt.Sprintf(
-		`INSERT INTO g2_games (server_seed,server_seed_hash, game) 
-				VALUES ('%s', '%s', '%s')`,
-		serverSeed,
-		serverSeedHash,
-		string(gameJSON),
-	)
-	// gRPC Call Insert User
-	res, err := grpcclient.SendQuery(query)
-	if err != nil || res == nil || res.Status != "ok" {
 		log.Fatalln("DB_DATA:", err)
 	}
-	dataDB := res.Data.GetFields()
-	newID := int64(dataDB["inserted_id"].GetNumberValue())
-	if newID < 1 {
-		log.Fatalln("DB_DATA:", err)
+	if newID != id {
+		log.Printf("Room %s game %d expected, table gave %d", r.ID, id, newID)
 	}
 
 	// Update Game ID
 	newGame.ID = newID
-
-	// Clear old Bets
-	LiveBets = make(map[int64][]models.Bet)
-	BetsByMultiplier = make(map[int][]models.Bet)
-
-	// Waiting for bets
-	LiveGame = &models.LiveGame{
-		ID:             newGame.ID,
-		GameState:      StateWaiting,
-		ServerSeedHash: newGame.ServerSeed,
-		Multiplier:     newGame.Multiplier,
-		ServerTime:     time.Now().UnixMilli(),
-		Tracker:        he.NewTracker(),
+	newGame.Nonce = newID
+
+	// Risk levers are decided before betting opens and never touch the crash point
+	avgHE, heKnown := r.Games.AvgHE(30)
+	decision := risk.Decide(newGame.ID, avgHE, heKnown)
+	decision.Policy = decision.Policy.Scale(r.LimitScale)
+	newGame.Risk = &decision
+
+	// Distribution profile is one of the risk levers, pinned to its current version
+	profile, ok := provablyfair.GetProfile(decision.Policy.Profile, 0)
+	if !ok {
+		log.Printf("Game %d profile %s not found, using default", newGame.ID, decision.Policy.Profile)
+		profile = provablyfair.DefaultProfile()
 	}
-	log.Printf("Game %d waiting for bets", newGame.ID)
-	events.Emit("all", "liveGame", LiveGame)
-	time.Sleep(15000 * time.Millisecond)
+	newGame.Profile = profile.Name
+	newGame.ProfileVersion = profile.Version
+
+	// Waiting for bets, old bets are dropped by the engine
+	live := r.Engine.Open(models.LiveGame{
+		ID:                 newGame.ID,
+		Room:               r.ID,
+		ServerSeedHash:     newGame.ServerSeedHash,
+		NextServerSeedHash: next.hash,
+		ClientSeed:         newGame.ClientSeed,
+		Nonce:              newGame.Nonce,
+		Profile:            newGame.Profile,
+		ProfileVersion:     newGame.ProfileVersion,
+		Multiplier:         newGame.Multiplier,
+		Limits:             decision.Policy,
+		Tracker:            he.NewTracker(),
+	})
+	log.Printf("Room %s game %d waiting for bets", r.ID, newGame.ID)
+	r.Emit("liveGame", live)
+	r.countdown(newGame.ID, timings.Betting())
 
 	// Force Start
-	LiveGame.GameState = StateRunning
-	log.Printf("Game %d running to %.2f", newGame.ID, newGame.CrashAt)
-	startGameLoop(newGame)
-}
-
-func startGameLoop(game models.Game) {
-	events.Emit("all", "liveGame", LiveGame)
-	time.Sleep(2000 * time.Millisecond)
-
-	go func() {
-		speed := 550
-		multiplier := 1.
-		for {
-			if LiveGame == nil || LiveGame.GameState != StateRunning {
-				game.EndAt = time.Now().UTC()
-				go endGame(game)
-				break
-			}
-
-			if speed > 10 {
-
-				speed--
-
-				if game.CrashAt > 10 {
-					speed--
-				}
-
-				if game.CrashAt > 20 {
-					speed--
-				}
-
-				if game.CrashAt > 30 {
-					speed--
-				}
+	bets := r.Engine.CloseBetting()
 
-				if game.CrashAt > 40 {
-					speed--
-				}
-
-				if game.CrashAt > 50 {
-					speed--
-				}
-
-				if game.CrashAt > 60 {
-					speed--
-				}
-
-			}
-
-			if speed > 50 {
-				speed--
-			}
+	// The first bets of this round fix the client seed of the next one, whose hash they saw
+	r.fixNextClientSeed(bets)
+	newGame.CrashAt = utils.RoundToTwoDigits(
+		provablyfair.VerifyRound(profile, newGame.ServerSeed, newGame.ClientSeed, newGame.Nonce).Multiplier,
+	)
+	r.Engine.SetCrashPoint(newGame.ClientSeed, newGame.CrashAt)
 
-			if speed > 100 {
-				speed--
-			}
+	// Record the crash point, boot recovery settles against it if the process dies mid-round
+	r.saveGame(newGame)
 
-			if speed > 175 {
-				speed--
-			}
+	log.Printf("Room %s game %d running to %.2f", r.ID, newGame.ID, newGame.CrashAt)
+	r.startGameLoop(newGame, timings)
+}
 
-			if speed > 250 {
-				speed--
-			}
+func (r *Room) startGameLoop(game models.Game, timings PhaseTimings) {
+	r.Emit("liveGame", r.Engine.Live())
+	time.Sleep(timings.PreRun())
 
-			if speed > 350 {
-				speed--
-			}
+	// Multiplier is a published function of the time since start, evaluated on a fixed tick
+	curve := timings.Curve()
+	r.Engine.Start(curve, time.Now())
 
-			if speed > 400 {
-				speed--
+	go func() {
+		ticker := time.NewTicker(curve.Tick)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			due, multiplier, crashed := r.Engine.Tick(now)
+
+			if len(due) > 0 {
+				r.payouts.Add(1)
+				go func(due []models.Bet, multiplier float64) {
+					defer r.payouts.Done()
+					defer func() {
+						if r := recover(); r != nil {
+							log.Println("panic in ProcessStep:", r)
+						}
+					}()
+					r.processStep(due, multiplier)
+				}(due, multiplier)
 			}
 
-			if speed > 450 {
-				speed--
+			if crashed {
+				log.Printf("Room %s game %d crashd", r.ID, game.ID)
+				// Stored before the seed is revealed, recovery settles a crashed round as lost
+				game.EndAt = time.Now().UTC()
+				r.saveGame(game)
+				r.Emit("crash", nil)
+				r.revealRound(game)
 			}
+			r.Emit("liveGame", r.Engine.Live())
 
-			time.Sleep(time.Duration(speed) * time.Millisecond)
-			multiplier += 0.01
-			LiveGame.Multiplier = utils.RoundToTwoDigits(multiplier)
-			LiveGame.ServerTime = time.Now().UnixMilli()
-
-			go func() {
-				defer func() {
-					if r := recover(); r != nil {
-						log.Println("panic in ProcessStep:", r)
-					}
-				}()
-				processStep(multiplier)
-			}()
-
-			if LiveGame.Multiplier >= game.CrashAt {
-				log.Printf("Game %d crashd", game.ID)
-				events.Emit("all", "crash", nil)
-				LiveGame.GameState = StateCrashed
-				LiveGame.Multiplier = game.CrashAt
+			if crashed {
+				go r.endGame(game, timings)
+				break
 			}
-			events.Emit("all", "liveGame", LiveGame)
 		}
 	}()
 }
 
-func endGame(game models.Game) {
-	time.Sleep(3000 * time.Millisecond)
+func (r *Room) endGame(game models.Game, timings PhaseTimings) {
+	time.Sleep(timings.PostCrash())
 
-	// Update DB
-	gameJSON, err := json.Marshal(game)
-	if err != nil {
-		log.Fatalln("Failed to marshal game:", err)
-	}
-	// Sanitize and build query
-	query := fmt.Sprintf(
-		`Update g2_games SET game = '%s', is_live=0 WHERE id = %d`,
-		string(gameJSON),
-		game.ID,
-	)
-	res, err := grpcclient.SendQuery(query)
-	if err != nil || res == nil || res.Status != "ok" {
-		log.Fatalln("GRPC_ERROR", err)
-	}
-	dataDB := res.Data.GetFields()
-	exist := dataDB["rows_affected"].GetNumberValue()
-	if exist == 0 {
-		log.Fatalln("NOT_UPDATED", dataDB)
-	}
-	LiveGame.GameState = StateFinished
-	// events.Emit("all", "liveGame", LiveGame)
+	// Cashouts of this round must be settled before it is stored, retried credits included
+	r.payouts.Wait()
+	r.Engine.WaitSettled()
 
-	LiveGame.Tracker.Save("g2_games", int(game.ID))
+	// Game, final bets and tracker totals are stored in one transaction
+	game.Status = GameStatusFinished
+	if err := r.Games.Finish(game, r.Engine.AllBets(), r.Engine.Live().Tracker); err != nil {
+		log.Fatalln("GRPC_ERROR game", game.ID, err)
+	}
+	r.Engine.Finish()
 
 	// time.Sleep(1000 * time.Millisecond)
-	log.Printf("Game %d Ended", game.ID)
+	log.Printf("Room %s game %d Ended", r.ID, game.ID)
 
 	// Emit History
-	History.Add(game.CrashAt)
-	events.Emit("all", "history", History.GetAll())
+	r.History.Add(game.CrashAt)
+	r.Emit("history", r.History.GetAll())
+
+	// Stop here when draining, the round is fully stored
+	if r.Draining() || !r.waitWhilePaused() {
+		close(r.drained)
+		return
+	}
 
 	// Call Next Game
-	NextGame(game.ID + 1)
+	r.NextGame(game.ID + 1)
+}
+
+// saveGame stores the row of the live game, endGame finishes it
+func (r *Room) saveGame(game models.Game) {
+	if err := r.Games.Update(game, true); err != nil {
+		log.Fatalln("GRPC_ERROR game", game.ID, err)
+	}
 }
@@ -1,17 +1,17 @@
 package handlers
 
 import (
-	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/events"
+	"sync"
+
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
 )
 
 type CrashHistory struct {
 	data []float64
 	size int
+	mu   sync.Mutex
 }
 
-var History = NewCrashHistory(50)
-
 func NewCrashHistory(limit int) *CrashHistory {
 	return &CrashHistory{
 		data: make([]float64, 0, limit),
@@ -20,6 +20,8 @@ func NewCrashHistory(limit int) *CrashHistory {
 }
 
 func (h *CrashHistory) Add(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	if len(h.data) >= h.size {
 		h.data = h.data[1:]
 	}
@@ -27,16 +29,24 @@ func (h *CrashHistory) Add(value float64) {
 }
 
 func (h *CrashHistory) GetAll() []float64 {
-	return h.data
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]float64, len(h.data))
+	copy(out, h.data)
+	return out
 }
 
-func GetHistory(_ map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+func GetHistory(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 	var (
 		errR models.HandlerError
 		resR models.HandlerOK
 	)
 
-	events.Emit("all", "history", History.GetAll())
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return resR, rErr
+	}
+	room.Emit("history", room.History.GetAll())
 
 	// Success
 	resR.Type = "getHistory"
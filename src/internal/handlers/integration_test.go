@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/fakecore"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/fakeum"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
+)
+
+// queryCore reads rows straight from the fake Core tables
+func queryCore(t *testing.T, core *fakecore.Server, sql string, params ...any) []map[string]any {
+	t.Helper()
+	rows, err := core.Store.Query(sql, params...)
+	if err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+	return rows
+}
+
+func TestRoundOnFakeCore(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		crash  func(crashAt float64) bool
+		state  string
+		payout float64
+	}{
+		{"target reached", func(m float64) bool { return m >= 1.2 }, models.BetWon, 12},
+		{"crash before the target", func(m float64) bool { return m < 1.2 }, models.BetLost, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			core, um := startBackends(t)
+			setRooms([]RoomConfig{fastRoomConfig()})
+			r := DefaultRoom()
+			um.AddUser(fakeum.User{ID: 7, DisplayName: "auto", Balance: 100, Token: "jwt-7"})
+			crashAt := fixCrash(t, r, tc.crash)
+
+			openRound(t, r)
+			bet := placeBet(t, "jwt-7", 10, 1.2)
+			r.Drain()
+
+			games := queryCore(t, core, "SELECT is_live, income, expense, game FROM g2_games WHERE id = ?", 1)
+			if len(games) != 1 {
+				t.Fatalf("game rows = %d, want 1", len(games))
+			}
+			var game models.Game
+			if err := json.Unmarshal([]byte(games[0]["game"].(string)), &game); err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(games[0]["is_live"]) != "0" || game.Status != GameStatusFinished || game.CrashAt != crashAt {
+				t.Fatalf("game row is_live=%v status=%q crashAt=%.2f, want crash at %.2f",
+					games[0]["is_live"], game.Status, game.CrashAt, crashAt)
+			}
+
+			bets := queryCore(t, core, "SELECT bet FROM g2_bets WHERE game_id = ?", 1)
+			if len(bets) != 1 {
+				t.Fatalf("bet rows = %d, want 1", len(bets))
+			}
+			var stored models.Bet
+			if err := json.Unmarshal([]byte(bets[0]["bet"].(string)), &stored); err != nil {
+				t.Fatal(err)
+			}
+			if stored.State != tc.state || stored.Payout != tc.payout {
+				t.Errorf("bet row %s paid %.2f at crash %.2f, want %s paid %.2f",
+					stored.State, stored.Payout, crashAt, tc.state, tc.payout)
+			}
+			if fmt.Sprint(games[0]["income"]) != "10" || fmt.Sprint(games[0]["expense"]) != fmt.Sprint(tc.payout) {
+				t.Errorf("game totals income=%v expense=%v, want 10 and %v", games[0]["income"], games[0]["expense"], tc.payout)
+			}
+
+			// The stake, and the credit of a won bet
+			entries := queryCore(t, core, "SELECT kind, status, amount FROM g2_outbox WHERE bet_id = ? ORDER BY id", bet.ID)
+			wantEntries := 1
+			if tc.payout > 0 {
+				wantEntries = 2
+			}
+			if len(entries) != wantEntries {
+				t.Fatalf("outbox entries = %v, want %d", entries, wantEntries)
+			}
+			for _, e := range entries {
+				if e["status"] != OutboxDone {
+					t.Errorf("outbox %s entry is %v", e["kind"], e["status"])
+				}
+			}
+			steps := queryCore(t, core, "SELECT step FROM g2_bet_saga WHERE bet_id = ? AND status = ?", bet.ID, SagaOK)
+			if len(steps) != 4 {
+				t.Errorf("saga steps = %v, want 4", steps)
+			}
+			if got := balance(t, um, 7); got != 90+tc.payout {
+				t.Errorf("balance = %.2f, want %.2f", got, 90+tc.payout)
+			}
+		})
+	}
+}
+
+func TestAddBetCheckoutMovesBalance(t *testing.T) {
+	core, um := startBackends(t)
+	setRooms([]RoomConfig{fastRoomConfig()})
+	r := DefaultRoom()
+	um.AddUser(fakeum.User{ID: 7, DisplayName: "manual", Balance: 100, Token: "jwt-7"})
+	// Far enough for the cashout to land before the crash
+	fixCrash(t, r, func(m float64) bool { return m >= 20 && m <= 35 })
+
+	openRound(t, r)
+	bet := placeBet(t, "jwt-7", 10, 1000)
+	if got := balance(t, um, 7); got != 90 {
+		t.Fatalf("balance after AddBet = %.2f, want 90.00", got)
+	}
+
+	waitFor(t, "the curve", func() bool { return r.Engine.Live().GameState != StateWaiting })
+	res, errR := CheckoutBet(map[string]interface{}{"token": "jwt-7", "betID": float64(bet.ID)})
+	if errR.Code != 0 || errR.Type != "" || res.Data != nil {
+		t.Fatalf("checkout = %+v, %+v", res.Data, errR)
+	}
+	r.Drain()
+
+	paid, err := r.Bets.Get(bet.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paid.State != models.BetWon || paid.CheckoutOn < 1 || paid.Payout != utils.RoundToTwoDigits(10*paid.CheckoutOn) {
+		t.Fatalf("cashed out bet %s on %.2f paid %.2f", paid.State, paid.CheckoutOn, paid.Payout)
+	}
+	if got, want := balance(t, um, 7), utils.RoundToTwoDigits(90+paid.Payout); got != want {
+		t.Errorf("balance after CheckoutBet = %.2f, want %.2f", got, want)
+	}
+
+	// UM saw the stake and one credit, referencing the bet
+	txs := um.Transactions()
+	if len(txs) != 2 {
+		t.Fatalf("UM transactions = %d, want 2", len(txs))
+	}
+	last := txs[1]
+	if last.Type != outboxTxTypes[OutboxWin] || last.Amount != paid.Payout ||
+		last.TxRef != strconv.FormatInt(paid.ID, 10) {
+		t.Errorf("last UM transaction = %+v, want the win of bet %d", last.UMTransactionData, paid.ID)
+	}
+	if stake := txs[0]; stake.Type != outboxTxTypes[OutboxDebit] || stake.Amount != 10 {
+		t.Errorf("first UM transaction = %+v, want a 10.00 stake", stake.UMTransactionData)
+	}
+	if rows := queryCore(t, core, "SELECT id FROM g2_outbox WHERE status <> ?", OutboxDone); len(rows) != 0 {
+		t.Errorf("outbox entries not done: %v", rows)
+	}
+}
@@ -9,17 +9,16 @@ import (
 
 // CrashLeaderboard keeps track of last N paid bets
 type CrashLeaderboard struct {
+	room string
 	data []models.Bet
 	size int
 	mu   sync.Mutex
 }
 
-// Leaderboard Global instance (limit 20 items)
-var Leaderboard = NewCrashLeaderboard(20)
-
 // NewCrashLeaderboard Constructor
-func NewCrashLeaderboard(limit int) *CrashLeaderboard {
+func NewCrashLeaderboard(room string, limit int) *CrashLeaderboard {
 	return &CrashLeaderboard{
+		room: room,
 		data: make([]models.Bet, 0, limit),
 		size: limit,
 	}
@@ -39,7 +38,7 @@ func (lb *CrashLeaderboard) Add(bet models.Bet) {
 	lb.mu.Unlock() // 🔓 unlock before logging/emitting
 
 	// emit outside the lock (non-blocking)
-	go events.Emit("all", "leaderboard", snapshot)
+	go events.EmitRoom(lb.room, "leaderboard", snapshot)
 }
 
 // GetAll returns a snapshot of leaderboard
@@ -54,13 +53,17 @@ func (lb *CrashLeaderboard) GetAll() []models.Bet {
 }
 
 // GetLeaderboard API handler for clients
-func GetLeaderboard(_ map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+func GetLeaderboard(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 	var (
 		errR models.HandlerError
 		resR models.HandlerOK
 	)
 
-	events.Emit("all", "leaderboard", Leaderboard.GetAll())
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return resR, rErr
+	}
+	room.Emit("leaderboard", room.Leaderboard.GetAll())
 
 	// Success -
 	resR.Type = "getLeaderboard"
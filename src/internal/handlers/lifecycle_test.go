@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/fakeum"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/provablyfair"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/repository"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/risk"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
+)
+
+// fastRoomConfig is the default room with rounds of about a second
+func fastRoomConfig() RoomConfig {
+	cfg := defaultRoomConfig()
+	cfg.Timings = PhaseTimings{BettingMs: 1000, PreRunMs: 1, PostCrashMs: 1, CurveRate: 0.01, TickMs: 10}
+	return cfg
+}
+
+// memoryBackends holds the stores of a room running without Core
+type memoryBackends struct {
+	games  *repository.MemoryGames
+	outbox *repository.MemoryOutbox
+	sagas  *repository.MemorySagas
+}
+
+// startMemory runs the default room with fast timings on the memory repositories and
+// points UM at a fake
+func startMemory(t *testing.T) (*Room, *fakeum.Server, memoryBackends) {
+	t.Helper()
+	um := fakeum.Start()
+	t.Cleanup(um.Close)
+	t.Setenv("API_UM", um.Env())
+
+	setRooms([]RoomConfig{fastRoomConfig()})
+	r := DefaultRoom()
+
+	mem := memoryBackends{outbox: repository.NewMemoryOutbox(), sagas: repository.NewMemorySagas()}
+	mem.games, r.Bets = repository.NewMemory()
+	r.Games = mem.games
+
+	coreOutbox, coreSagas := outbox, sagas
+	outbox, sagas = mem.outbox, mem.sagas
+	t.Cleanup(func() { outbox, sagas = coreOutbox, coreSagas })
+	return r, um, mem
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// fixCrash commits a fixed server seed for game 1 of the room, picked so the round crashes
+// at a point that holds cond. Game 1 has no client seed. It returns the crash point.
+func fixCrash(t *testing.T, r *Room, cond func(crashAt float64) bool) float64 {
+	t.Helper()
+	profile, ok := provablyfair.GetProfile(risk.Tiers[risk.TierStandard].Profile, 0)
+	if !ok {
+		t.Fatal("standard profile not found")
+	}
+	for i := 0; i < 1000; i++ {
+		seed := fmt.Sprintf("%064x", i+1)
+		crashAt := provablyfair.VerifyRound(profile, seed, "", 1).Multiplier
+		if !cond(crashAt) {
+			continue
+		}
+		r.seedMu.Lock()
+		r.nextSeed = &roundSeed{seed: seed, hash: provablyfair.HashServerSeed(seed)}
+		r.nextClientSeed = ""
+		r.seedMu.Unlock()
+		return crashAt
+	}
+	t.Fatal("no test seed crashes as wanted")
+	return 0
+}
+
+// openRound starts the round loop of the room and waits for betting on game 1
+func openRound(t *testing.T, r *Room) {
+	t.Helper()
+	go r.NextGame(1)
+	waitFor(t, "betting", func() bool {
+		live := r.Engine.Live()
+		return live.ID == 1 && live.GameState == StateWaiting
+	})
+}
+
+// placeBet calls AddBet for the user of the token
+func placeBet(t *testing.T, token string, bet, multiplier float64) models.Bet {
+	t.Helper()
+	res, errR := AddBet(map[string]interface{}{"token": token, "bet": bet, "multiplier": multiplier})
+	if errR.Code != 0 || errR.Type != "" {
+		t.Fatalf("AddBet %s: %+v", token, errR)
+	}
+	return res.Data.(models.Bet)
+}
+
+func TestRoundLifecycleInMemory(t *testing.T) {
+	r, um, mem := startMemory(t)
+	um.AddUser(fakeum.User{ID: 7, DisplayName: "manual", Balance: 100, Token: "jwt-7"})
+	um.AddUser(fakeum.User{ID: 8, DisplayName: "auto", Balance: 100, Token: "jwt-8"})
+	// Far enough above 1.5 for the manual cashout to land before the crash
+	crashAt := fixCrash(t, r, func(m float64) bool { return m >= 20 && m <= 35 })
+
+	openRound(t, r)
+	manual := placeBet(t, "jwt-7", 10, 1000)
+	auto := placeBet(t, "jwt-8", 20, 1.5)
+	for userID, want := range map[int]float64{7: 90, 8: 80} {
+		if got := balance(t, um, userID); got != want {
+			t.Errorf("user %d balance after betting = %.2f, want %.2f", userID, got, want)
+		}
+	}
+
+	waitFor(t, "the curve", func() bool { return r.Engine.Live().GameState != StateWaiting })
+	_, cashout := CheckoutBet(map[string]interface{}{"token": "jwt-7", "betID": float64(manual.ID)})
+	r.Drain()
+	if cashout.Code != 0 || cashout.Type != "" {
+		t.Fatalf("cashout before a crash at %.2f: %+v", crashAt, cashout)
+	}
+
+	game, live, err := r.Games.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if live || game.Status != GameStatusFinished || game.CrashAt != crashAt {
+		t.Fatalf("game 1 stored live=%v status=%q crashAt=%.2f, want crash at %.2f", live, game.Status, game.CrashAt, crashAt)
+	}
+
+	// Every placed bet went through the whole saga
+	for _, bet := range []models.Bet{manual, auto} {
+		var steps []string
+		for _, step := range mem.sagas.Steps(bet.ID) {
+			if step.Status != SagaOK {
+				t.Errorf("bet %d saga step %s %s: %s", bet.ID, step.Step, step.Status, step.Detail)
+			}
+			steps = append(steps, step.Step)
+		}
+		if len(steps) != 4 || steps[0] != SagaInsert || steps[3] != SagaPlace {
+			t.Errorf("bet %d saga steps = %v", bet.ID, steps)
+		}
+	}
+
+	// The manual bet is paid where it cashed out, the auto bet at its target
+	stored, err := r.Bets.Get(manual.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.State != models.BetWon || stored.CheckoutBy != "User" || stored.Payout != utils.RoundToTwoDigits(10*stored.CheckoutOn) {
+		t.Errorf("cashed out bet stored %s by %q paid %.2f at %.2f", stored.State, stored.CheckoutBy, stored.Payout, stored.CheckoutOn)
+	}
+	paidAuto, err := r.Bets.Get(auto.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paidAuto.State != models.BetWon || paidAuto.Payout != 30 {
+		t.Errorf("auto bet stored %s paid %.2f, want won 30.00", paidAuto.State, paidAuto.Payout)
+	}
+
+	// UM holds the stakes and the credits recorded in the outbox
+	expense := 0.
+	for userID, bet := range map[int]models.Bet{7: stored, 8: paidAuto} {
+		expense += bet.Payout
+		want := utils.RoundToTwoDigits(100 - bet.Bet + bet.Payout)
+		if got := balance(t, um, userID); got != want {
+			t.Errorf("user %d balance = %.2f, want %.2f", userID, got, want)
+		}
+		win, won, _ := mem.outbox.Find(OutboxKey(r.ID, 1, bet.ID, OutboxWin))
+		if !won || win.Status != OutboxDone || win.Amount != bet.Payout {
+			t.Errorf("bet %d payout %.2f has win entry %v %+v", bet.ID, bet.Payout, won, win)
+		}
+	}
+	if got := len(um.XpChanges()); got != 2 {
+		t.Errorf("XP changes = %d, want 2", got)
+	}
+
+	stats, ok := mem.games.Stats(1)
+	if !ok || stats.Income != 30 || math.Abs(stats.Expense-expense) > 0.001 {
+		t.Errorf("game stats = %+v, want income 30 and expense %.2f", stats, expense)
+	}
+}
+
+func TestRoundCrashingBeforeTheTargetInMemory(t *testing.T) {
+	r, um, mem := startMemory(t)
+	um.AddUser(fakeum.User{ID: 8, DisplayName: "auto", Balance: 100, Token: "jwt-8"})
+	crashAt := fixCrash(t, r, func(m float64) bool { return m < 1.5 })
+
+	openRound(t, r)
+	auto := placeBet(t, "jwt-8", 20, 1.5)
+	r.Drain()
+
+	game, _, err := r.Games.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Status != GameStatusFinished || game.CrashAt != crashAt {
+		t.Fatalf("game 1 stored status=%q crashAt=%.2f, want crash at %.2f", game.Status, game.CrashAt, crashAt)
+	}
+
+	lost, err := r.Bets.Get(auto.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lost.State != models.BetLost || lost.Payout != 0 {
+		t.Errorf("auto bet stored %s paid %.2f, want lost", lost.State, lost.Payout)
+	}
+	if got := balance(t, um, 8); got != 80 {
+		t.Errorf("balance = %.2f, want 80.00", got)
+	}
+	if _, won, _ := mem.outbox.Find(OutboxKey(r.ID, 1, auto.ID, OutboxWin)); won {
+		t.Error("lost bet has a win entry")
+	}
+
+	stats, ok := mem.games.Stats(1)
+	if !ok || stats.Income != 20 || stats.Expense != 0 {
+		t.Errorf("game stats = %+v, want income 20 and no expense", stats)
+	}
+}
+
+// creditlessOutbox refuses every win entry, like a Core that cannot store them
+type creditlessOutbox struct {
+	repository.OutboxRepository
+}
+
+func (o creditlessOutbox) Enqueue(e OutboxEntry) (OutboxEntry, error) {
+	if e.Kind == OutboxWin {
+		return e, errors.New("core unavailable")
+	}
+	return o.OutboxRepository.Enqueue(e)
+}
+
+func TestUnrecordedCreditLeavesBetUnpaid(t *testing.T) {
+	r, um, mem := startMemory(t)
+	um.AddUser(fakeum.User{ID: 7, DisplayName: "auto", Balance: 100, Token: "jwt-7"})
+	outbox = creditlessOutbox{mem.outbox}
+	retryMin, attempts := payoutRetryMin, payoutAttempts
+	payoutRetryMin, payoutAttempts = time.Millisecond, 3
+	t.Cleanup(func() { payoutRetryMin, payoutAttempts = retryMin, attempts })
+
+	// The legacy profile never crashes below 1.01, the target is always reached
+	openRound(t, r)
+	bet := placeBet(t, "jwt-7", 10, 1.01)
+	r.Drain()
+
+	stored, err := r.Bets.Get(bet.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.State != models.BetUnpaid || stored.Payout != 10.1 || stored.CheckoutOn != 1.01 {
+		t.Errorf("bet stored %s with payout %.2f at %.2f, want unpaid 10.10 at 1.01", stored.State, stored.Payout, stored.CheckoutOn)
+	}
+	if got := balance(t, um, 7); got != 90 {
+		t.Errorf("balance = %.2f, want 90.00 until reconciliation pays", got)
+	}
+	if _, found, _ := mem.outbox.Find(OutboxKey(r.ID, 1, bet.ID, OutboxWin)); found {
+		t.Error("refused credit has a win entry")
+	}
+
+	// The expense is only counted for settled credits
+	stats, ok := mem.games.Stats(1)
+	if !ok || stats.Income != 10 || stats.Expense != 0 {
+		t.Errorf("game stats = %+v, want income 10 and no expense", stats)
+	}
+}
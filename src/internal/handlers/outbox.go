@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/repository"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/validate"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
+)
+
+// outboxTable holds every UM transaction of the game, recorded before it is sent.
+// Its DDL is migrations/001_g2_outbox.sql.
+const outboxTable = "g2_outbox"
+
+// outbox stores the entries of every room
+var outbox repository.OutboxRepository = repository.NewCoreOutbox(outboxTable)
+
+// Outbox entry kinds
+const (
+	OutboxDebit      = repository.OutboxDebit
+	OutboxWin        = repository.OutboxWin
+	OutboxRefund     = repository.OutboxRefund
+	OutboxCorrection = repository.OutboxCorrection
+)
+
+// Outbox entry statuses
+const (
+	OutboxPending = repository.OutboxPending
+	OutboxSending = repository.OutboxSending
+	OutboxDone    = repository.OutboxDone
+	OutboxFailed  = repository.OutboxFailed
+	OutboxUnknown = repository.OutboxUnknown
+)
+
+// OutboxEntry is one UM transaction of a bet
+type OutboxEntry = repository.OutboxEntry
+
+// UM transaction types of the outbox kinds. UM defines game_loss and game_win only, a
+// refund is a game_win credit told apart by its outbox kind and description.
+var outboxTxTypes = map[string]string{
+	OutboxDebit:  "game_loss",
+	OutboxWin:    "game_win",
+	OutboxRefund: "game_win",
+}
+
+// outboxDescription is the UM description of an entry
+func outboxDescription(e OutboxEntry) string {
+	if e.Kind == OutboxRefund {
+		return "Crash refund"
+	}
+	return "Crash"
+}
+
+// Delivery worker defaults
+const (
+	defaultOutboxPoll        = 2 * time.Second
+	defaultOutboxMaxAttempts = 12
+	outboxBatch              = 50
+	outboxMaxBackoff         = 5 * time.Minute
+	outboxListLimit          = 200
+)
+
+var errOutboxClaimed = errors.New("outbox entry claimed by another sender")
+
+// errOutboxUnknown is returned for an entry UM may have applied, it is never sent again
+// automatically: the UM contract has no idempotency key nor a transaction lookup.
+var errOutboxUnknown = errors.New("outbox entry outcome unknown")
+
+var (
+	outboxStop = make(chan struct{})
+	outboxDone = make(chan struct{})
+)
+
+// UMError is a transaction UM answered with a failure status
+type UMError struct {
+	Type string
+	Code int
+	Data interface{}
+}
+
+func (e *UMError) Error() string {
+	return fmt.Sprintf("UM %s:%d", e.Type, e.Code)
+}
+
+// OutboxKey is the idempotency key of a bet transaction, bet IDs are per room table
+func OutboxKey(room string, gameID, betID int64, kind string) string {
+	return fmt.Sprintf("%s:%d:%d:%s", room, gameID, betID, kind)
+}
+
+// enqueue records a transaction of the bet. Enqueuing a key twice keeps the first entry,
+// which is returned.
+func (r *Room) enqueue(kind string, bet models.Bet, amount float64) (OutboxEntry, error) {
+	return r.enqueueTx(OutboxKey(r.ID, bet.GameID, bet.ID, kind), kind, outboxTxTypes[kind], bet, amount)
+}
+
+// enqueueTx records a transaction of the bet under its own key and UM type
+func (r *Room) enqueueTx(key, kind, txType string, bet models.Bet, amount float64) (OutboxEntry, error) {
+	entry, err := outbox.Enqueue(OutboxEntry{
+		Key:         key,
+		Room:        r.ID,
+		Kind:        kind,
+		UserID:      bet.UserID,
+		GameID:      bet.GameID,
+		BetID:       bet.ID,
+		TxType:      txType,
+		ReferenceID: referenceOf(bet, txType),
+		TxRef:       strconv.FormatInt(bet.ID, 10),
+		Amount:      amount,
+	})
+	if err != nil {
+		return entry, fmt.Errorf("enqueue %s: %v", key, err)
+	}
+	return entry, nil
+}
+
+// deliverOutbox sends an entry to UM once it is claimed. A credit UM rejected is
+// rescheduled with backoff, a rejected debit is not retried. An entry sent without an
+// answer is left unknown.
+func deliverOutbox(e OutboxEntry) error {
+	claimed, err := outbox.Move(e.ID, OutboxPending, repository.OutboxUpdate{
+		Status:    OutboxSending,
+		Attempts:  e.Attempts,
+		LastError: e.LastError,
+	})
+	if err != nil {
+		return fmt.Errorf("claim %s: %v", e.Key, err)
+	}
+	if !claimed {
+		return errOutboxClaimed
+	}
+
+	Transaction, err := utils.AddTransaction(
+		int(e.UserID),
+		e.TxType,
+		e.ReferenceID,
+		e.Amount,
+		e.TxRef,
+		outboxDescription(e),
+	)
+	if err == nil {
+		errCode, status, errType := utils.SafeExtractErrorStatus(Transaction)
+		if status != 1 {
+			err = &UMError{Type: errType, Code: errCode, Data: Transaction["data"]}
+		}
+	}
+	if errors.Is(err, utils.ErrNoAnswer) {
+		if uErr := unknownOutbox(e, err.Error()); uErr != nil {
+			log.Println("outbox >", e.Key, uErr)
+		}
+		return fmt.Errorf("%w: %v", errOutboxUnknown, err)
+	}
+	if err != nil {
+		if fErr := failOutbox(e, err.Error()); fErr != nil {
+			log.Println("outbox >", e.Key, fErr)
+		}
+		return err
+	}
+
+	if _, dErr := outbox.Move(e.ID, OutboxSending, repository.OutboxUpdate{
+		Status:   OutboxDone,
+		Attempts: e.Attempts + 1,
+	}); dErr != nil {
+		// Left sending, a restart marks it unknown
+		log.Println("outbox >", e.Key, "sent but not marked done:", dErr)
+	}
+	return nil
+}
+
+// unknownOutbox records an attempt UM may have applied
+func unknownOutbox(e OutboxEntry, reason string) error {
+	_, err := outbox.Move(e.ID, OutboxSending, repository.OutboxUpdate{
+		Status:    OutboxUnknown,
+		Attempts:  e.Attempts + 1,
+		LastError: reason,
+	})
+	return err
+}
+
+// failOutbox records a failed attempt and schedules the next one
+func failOutbox(e OutboxEntry, reason string) error {
+	next := repository.OutboxUpdate{
+		Status:    OutboxFailed,
+		Attempts:  e.Attempts + 1,
+		LastError: reason,
+	}
+	if e.Kind != OutboxDebit && next.Attempts < outboxMaxAttempts() {
+		next.Status = OutboxPending
+		next.Delay = outboxBackoff(next.Attempts)
+	}
+	_, err := outbox.Move(e.ID, OutboxSending, next)
+	return err
+}
+
+// retryOutbox puts a failed credit back in the queue, failed debits stay failed
+func retryOutbox(id int64) (bool, error) {
+	e, found, err := outbox.Get(id)
+	if err != nil || !found || e.Kind == OutboxDebit {
+		return false, err
+	}
+	return outbox.Move(id, OutboxFailed, repository.OutboxUpdate{
+		Status:    OutboxPending,
+		LastError: e.LastError,
+	})
+}
+
+// resolveOutbox settles an unknown entry once an admin checked UM. An applied entry is
+// done, a credit UM did not apply is queued again and a debit it did not apply fails.
+func resolveOutbox(id int64, applied bool) (bool, error) {
+	e, found, err := outbox.Get(id)
+	if err != nil || !found {
+		return false, err
+	}
+	next := repository.OutboxUpdate{Status: OutboxDone, Attempts: e.Attempts}
+	switch {
+	case applied:
+	case e.Kind == OutboxDebit:
+		next.Status = OutboxFailed
+		next.LastError = e.LastError
+	default:
+		next.Status = OutboxPending
+		next.Attempts = 0
+		next.LastError = e.LastError
+	}
+	return outbox.Move(id, OutboxUnknown, next)
+}
+
+// sendOutbox delivers a credit right away, on failure the worker retries it
+func sendOutbox(e OutboxEntry) {
+	if e.Status != OutboxPending {
+		return
+	}
+	err := deliverOutbox(e)
+	switch {
+	case err == nil, errors.Is(err, errOutboxClaimed):
+	case errors.Is(err, errOutboxUnknown):
+		log.Printf("outbox > %s left unknown: %v", e.Key, err)
+	default:
+		log.Printf("outbox > %s failed, retried later: %v", e.Key, err)
+	}
+}
+
+// outboxBackoff doubles the delay after each failed attempt, from 2s up to 5 minutes
+func outboxBackoff(attempts int) time.Duration {
+	d := 2 * time.Second
+	for i := 1; i < attempts && d < outboxMaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, outboxMaxBackoff)
+}
+
+// outboxMaxAttempts reads OUTBOX_MAX_ATTEMPTS, a credit failing that often is left failed
+func outboxMaxAttempts() int {
+	n, err := strconv.Atoi(os.Getenv("OUTBOX_MAX_ATTEMPTS"))
+	if err != nil || n < 1 {
+		return defaultOutboxMaxAttempts
+	}
+	return n
+}
+
+// outboxPoll reads OUTBOX_POLL_MS, how often the worker looks for due credits
+func outboxPoll() time.Duration {
+	ms, err := strconv.Atoi(os.Getenv("OUTBOX_POLL_MS"))
+	if err != nil || ms < 1 {
+		return defaultOutboxPoll
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// StartOutbox settles the entries a previous process left in flight and starts the
+// delivery worker. It must run before StartRooms, recovery reads the outbox.
+func StartOutbox() {
+	// A debit never sent belongs to a bet that was never placed
+	if _, err := outbox.MoveAll(OutboxDebit, OutboxPending, OutboxFailed, "interrupted"); err != nil {
+		log.Fatalln("OUTBOX:", err)
+	}
+	// A sent debit or credit may have reached UM, it is left for an admin
+	if _, err := outbox.MoveAll("", OutboxSending, OutboxUnknown, "interrupted while sending"); err != nil {
+		log.Fatalln("OUTBOX:", err)
+	}
+
+	log.Println("📮 [outbox] delivery worker every", outboxPoll())
+	go outboxLoop()
+}
+
+// StopOutbox stops the worker after its current batch, pending entries stay stored
+func StopOutbox() {
+	close(outboxStop)
+	<-outboxDone
+}
+
+func outboxLoop() {
+	defer close(outboxDone)
+	ticker := time.NewTicker(outboxPoll())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-outboxStop:
+			return
+		case <-ticker.C:
+			flushOutbox()
+		}
+	}
+}
+
+// flushOutbox sends the credits that are due
+func flushOutbox() {
+	entries, err := outbox.ListDue(outboxBatch)
+	if err != nil {
+		log.Println("outbox >", err)
+		return
+	}
+	for _, e := range entries {
+		if err := deliverOutbox(e); err != nil && !errors.Is(err, errOutboxClaimed) {
+			log.Printf("outbox > %s attempt %d failed: %v", e.Key, e.Attempts+1, err)
+		}
+	}
+}
+
+// GetOutbox admin API handler listing stuck entries: failed and unknown ones and credits
+// that already failed an attempt. An optional status lists every entry in that status.
+func GetOutbox(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	if aErr, ok := requireAdmin(data); !ok {
+		return resR, aErr
+	}
+
+	var (
+		entries []OutboxEntry
+		err     error
+	)
+	if _, exists := data["status"]; exists {
+		status, vErr, ok := validate.RequireString(data, "status", false)
+		if !ok {
+			return resR, vErr
+		}
+		if !utils.InArray([]string{OutboxPending, OutboxSending, OutboxDone, OutboxFailed, OutboxUnknown}, status) {
+			errR.Type = "INVALID_TYPE_OR_FORMAT"
+			errR.Code = 5003
+			errR.Data = map[string]any{
+				"fieldName": "status",
+				"fieldType": "string",
+			}
+			return resR, errR
+		}
+		entries, err = outbox.ListByStatus(status, outboxListLimit)
+	} else {
+		entries, err = outbox.ListStuck(outboxListLimit)
+	}
+	if err != nil {
+		errR.Type = "DB_ERROR_GRPC"
+		errR.Code = 8000
+		return resR, errR
+	}
+
+	// Success
+	resR.Type = "getOutbox"
+	resR.Data = map[string]interface{}{
+		"entries": entries,
+	}
+	return resR, errR
+}
+
+// RetryOutbox admin API handler, a failed credit is sent again by the worker
+func RetryOutbox(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	if aErr, ok := requireAdmin(data); !ok {
+		return resR, aErr
+	}
+	id, vErr, ok := validate.RequireInt(data, "id")
+	if !ok {
+		return resR, vErr
+	}
+
+	moved, err := retryOutbox(id)
+	if err != nil {
+		errR.Type = "DB_ERROR_GRPC"
+		errR.Code = 8000
+		return resR, errR
+	}
+	if !moved {
+		errR.Type = "OUTBOX_NOT_RETRYABLE"
+		errR.Code = 8017
+		errR.Data = map[string]interface{}{
+			"id": id,
+		}
+		return resR, errR
+	}
+
+	// Success
+	resR.Type = "retryOutbox"
+	resR.Data = map[string]interface{}{
+		"id": id,
+	}
+	return resR, errR
+}
+
+// ResolveOutbox admin API handler settling an unknown entry. applied tells whether UM
+// shows the transaction: it is then done, otherwise a credit is sent again and a debit
+// fails. The reconciliation refunds the stake of a voided bet whose debit was applied.
+func ResolveOutbox(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	if aErr, ok := requireAdmin(data); !ok {
+		return resR, aErr
+	}
+	id, vErr, ok := validate.RequireInt(data, "id")
+	if !ok {
+		return resR, vErr
+	}
+	applied, vErr, ok := validate.RequireBool(data, "applied")
+	if !ok {
+		return resR, vErr
+	}
+
+	moved, err := resolveOutbox(id, applied)
+	if err != nil {
+		errR.Type = "DB_ERROR_GRPC"
+		errR.Code = 8000
+		return resR, errR
+	}
+	if !moved {
+		errR.Type = "OUTBOX_NOT_UNKNOWN"
+		errR.Code = 8018
+		errR.Data = map[string]interface{}{
+			"id": id,
+		}
+		return resR, errR
+	}
+
+	// Success
+	resR.Type = "resolveOutbox"
+	resR.Data = map[string]interface{}{
+		"id":      id,
+		"applied": applied,
+	}
+	return resR, errR
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/fakeum"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/he"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
+)
+
+// lostAnswer makes UM apply the next transaction and drop its answer
+var lostAnswer = fakeum.Failure{Disconnect: true, Apply: true, Times: 1}
+
+func TestUnansweredCreditIsNotSentAgain(t *testing.T) {
+	_, um := startBackends(t)
+	um.AddUser(fakeum.User{ID: 7})
+	r := DefaultRoom()
+	bet := storedBet(t, r, models.Bet{Room: r.ID, UserID: 7, GameID: 1, Bet: 10})
+
+	credit, err := r.enqueue(OutboxWin, bet, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	um.Fail(fakeum.AddTransaction, lostAnswer)
+	if err := deliverOutbox(credit); !errors.Is(err, errOutboxUnknown) {
+		t.Fatalf("deliver = %v, want %v", err, errOutboxUnknown)
+	}
+	if got := outboxStatus(t, credit.Key); got != OutboxUnknown {
+		t.Fatalf("status = %q, want %q", got, OutboxUnknown)
+	}
+
+	flushOutbox()
+	if got := len(um.Transactions()); got != 1 {
+		t.Fatalf("UM applied %d transactions, want 1", got)
+	}
+
+	// The admin finds it in UM
+	if moved, err := resolveOutbox(credit.ID, true); err != nil || !moved {
+		t.Fatalf("resolve = %v, %v", moved, err)
+	}
+	if moved, _ := resolveOutbox(credit.ID, true); moved {
+		t.Errorf("resolved a done entry")
+	}
+	flushOutbox()
+	if got := balance(t, um, 7); got != 20 {
+		t.Errorf("balance = %.2f, want 20.00", got)
+	}
+}
+
+func TestUnappliedCreditIsResolvedAndSent(t *testing.T) {
+	_, um := startBackends(t)
+	um.AddUser(fakeum.User{ID: 7})
+	r := DefaultRoom()
+	bet := storedBet(t, r, models.Bet{Room: r.ID, UserID: 7, GameID: 1, Bet: 10})
+
+	credit, err := r.enqueue(OutboxRefund, bet, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	um.Fail(fakeum.AddTransaction, fakeum.Failure{Disconnect: true, Times: 1})
+	sendOutbox(credit)
+	if got := outboxStatus(t, credit.Key); got != OutboxUnknown {
+		t.Fatalf("status = %q, want %q", got, OutboxUnknown)
+	}
+
+	// The admin does not find it in UM
+	if moved, err := resolveOutbox(credit.ID, false); err != nil || !moved {
+		t.Fatalf("resolve = %v, %v", moved, err)
+	}
+	flushOutbox()
+	if got := outboxStatus(t, credit.Key); got != OutboxDone {
+		t.Errorf("status = %q, want %q", got, OutboxDone)
+	}
+	if got := balance(t, um, 7); got != 10 {
+		t.Errorf("balance = %.2f, want 10.00", got)
+	}
+}
+
+func TestUnansweredDebitVoidsBetUntilResolved(t *testing.T) {
+	_, um := startBackends(t)
+	um.AddUser(fakeum.User{ID: 7, Balance: 100})
+	r := DefaultRoom()
+
+	game := models.Game{Room: r.ID, ServerSeedHash: "hash", CrashAt: 2}
+	gameID, err := r.Games.Insert(game)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.ID = gameID
+
+	// AddBet up to the debit
+	bet := storedBet(t, r, models.Bet{Room: r.ID, UserID: 7, GameID: gameID, Bet: 50, Multiplier: 2})
+	saga := r.newBetSaga(bet)
+	saga.ok(SagaInsert, "")
+	debit, err := r.enqueue(OutboxDebit, bet, bet.Bet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	um.Fail(fakeum.AddTransaction, lostAnswer)
+	err = deliverOutbox(debit)
+	saga.debitUnknown = errors.Is(err, errOutboxUnknown)
+	saga.fail(SagaDebit, err)
+
+	stored, err := r.Bets.Get(bet.ID)
+	if err != nil {
+		t.Fatalf("bet row of an unknown debit: %v", err)
+	}
+	if stored.State != models.BetVoided {
+		t.Errorf("bet state = %q, want %q", stored.State, models.BetVoided)
+	}
+	if got := outboxStatus(t, debit.Key); got != OutboxUnknown {
+		t.Fatalf("debit status = %q, want %q", got, OutboxUnknown)
+	}
+	if err := r.Games.Finish(game, nil, he.NewTracker()); err != nil {
+		t.Fatal(err)
+	}
+
+	from, to := time.Now().Add(-24*time.Hour), time.Now().Add(24*time.Hour)
+	report, err := Reconcile(from, to, r.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Unknown != 1 || len(report.Findings) != 0 {
+		t.Fatalf("unresolved report: %d unknown, findings %+v", report.Unknown, report.Findings)
+	}
+
+	// The admin finds the debit in UM, the reconciliation gives the stake back
+	if moved, err := resolveOutbox(debit.ID, true); err != nil || !moved {
+		t.Fatalf("resolve = %v, %v", moved, err)
+	}
+	if report, err = Reconcile(from, to, r.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if report.Corrected != 1 {
+		t.Fatalf("corrected = %d, findings %+v", report.Corrected, report.Findings)
+	}
+	flushOutbox()
+	if got := balance(t, um, 7); got != 100 {
+		t.Errorf("balance = %.2f, want 100.00", got)
+	}
+}
+
+func TestHungCreditTimesOutAsUnknown(t *testing.T) {
+	_, um := startBackends(t)
+	um.AddUser(fakeum.User{ID: 7})
+	r := DefaultRoom()
+	bet := storedBet(t, r, models.Bet{Room: r.ID, UserID: 7, GameID: 1, Bet: 10})
+
+	client := utils.UMClient
+	utils.UMClient = &http.Client{Timeout: 50 * time.Millisecond}
+	t.Cleanup(func() { utils.UMClient = client })
+	um.Delay(fakeum.AddTransaction, time.Minute)
+
+	credit, err := r.enqueue(OutboxWin, bet, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := deliverOutbox(credit); !errors.Is(err, errOutboxUnknown) {
+		t.Fatalf("deliver = %v, want %v", err, errOutboxUnknown)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("deliver returned after %s", elapsed)
+	}
+	if got := outboxStatus(t, credit.Key); got != OutboxUnknown {
+		t.Errorf("status = %q, want %q", got, OutboxUnknown)
+	}
+}
+
+func TestReconcileSkipsBetsBeforeTheOutbox(t *testing.T) {
+	_, um := startBackends(t)
+	um.AddUser(fakeum.User{ID: 7, Balance: 100})
+	r := DefaultRoom()
+
+	game := models.Game{Room: r.ID, ServerSeedHash: "hash", CrashAt: 2}
+	gameID, err := r.Games.Insert(game)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.ID = gameID
+
+	// A bet settled before the outbox existed, and a placed bet whose credit was never recorded
+	storedBet(t, r, models.Bet{Room: r.ID, UserID: 7, GameID: gameID, Bet: 10, Multiplier: 2,
+		State: models.BetWon, Payout: 20})
+	unpaid := storedBet(t, r, models.Bet{Room: r.ID, UserID: 7, GameID: gameID, Bet: 10, Multiplier: 2,
+		State: models.BetUnpaid, Payout: 20})
+	debit, err := r.enqueue(OutboxDebit, unpaid, unpaid.Bet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deliverOutbox(debit); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Games.Finish(game, nil, he.NewTracker()); err != nil {
+		t.Fatal(err)
+	}
+
+	from, to := time.Now().Add(-24*time.Hour), time.Now().Add(24*time.Hour)
+	report, err := Reconcile(from, to, r.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Bets != 2 || report.PreOutbox != 1 {
+		t.Errorf("report counts %d bets, %d before the outbox, want 2 and 1", report.Bets, report.PreOutbox)
+	}
+	if len(report.Findings) != 1 {
+		t.Fatalf("findings = %+v, want the unpaid credit only", report.Findings)
+	}
+	f := report.Findings[0]
+	if f.BetID != unpaid.ID || f.Kind != ReconMissing || f.Ledger != OutboxWin || f.Expected != 20 {
+		t.Errorf("finding = %+v, want the missing win of bet %d", f, unpaid.ID)
+	}
+}
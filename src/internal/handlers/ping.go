@@ -1,22 +1,26 @@
 package handlers
 
 import (
-	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/events"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
 	"time"
 )
 
 // Ping - Handler
-func Ping(_ map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+func Ping(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 	var (
 		errR models.HandlerError
 		resR models.HandlerOK
 	)
 
-	events.Emit("all", "history", History.GetAll())
-	events.Emit("all", "leaderboard", Leaderboard.GetAll())
-	events.Emit("all", "liveBets", LiveBets)
-	events.Emit("all", "liveGame", LiveGame)
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return resR, rErr
+	}
+	room.Emit("history", room.History.GetAll())
+	room.Emit("leaderboard", room.Leaderboard.GetAll())
+	live, bets := room.Engine.Snapshot()
+	room.Emit("liveBets", bets)
+	room.Emit("liveGame", live)
 
 	// Success
 	resR.Type = "ping"
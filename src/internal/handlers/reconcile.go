@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
+)
+
+// Reconciliation finding kinds
+const (
+	ReconMissing  = "missing"  // an amount of the bet row UM never confirmed
+	ReconMismatch = "mismatch" // the confirmed amount differs from the bet row
+)
+
+// Periodic reconciliation defaults
+const (
+	defaultReconcileInterval = time.Hour
+	reconcileLag             = 10 * time.Minute // finished games younger than this are left to the outbox
+)
+
+// reconLedgers are the amounts of a bet, named by the outbox kind that sends them
+var reconLedgers = []string{OutboxDebit, OutboxWin, OutboxRefund}
+
+// ReconFinding is one amount of a bet that does not match the transactions sent to UM
+type ReconFinding struct {
+	Kind     string  `json:"kind"`
+	Room     string  `json:"room"`
+	GameID   int64   `json:"gameID"`
+	BetID    int64   `json:"betID"`
+	UserID   int64   `json:"userID"`
+	Ledger   string  `json:"ledger"` // debit, win or refund
+	Expected float64 `json:"expected"`
+	Sent     float64 `json:"sent"`              // confirmed total, corrections included
+	Entries  []int64 `json:"entries,omitempty"` // outbox IDs of the amount
+	Queued   bool    `json:"queued"`            // a retry or correction is in the outbox
+}
+
+// ReconReport is the result of one reconciliation window
+type ReconReport struct {
+	From      time.Time      `json:"from"`
+	To        time.Time      `json:"to"`
+	Bets      int            `json:"bets"`
+	InFlight  int            `json:"inFlight"`  // bets with a transaction still being delivered, skipped
+	Unknown   int            `json:"unknown"`   // bets with a transaction UM may have applied, skipped until resolved
+	PreOutbox int            `json:"preOutbox"` // bets without any outbox entry, placed before the outbox, skipped
+	Findings  []ReconFinding `json:"findings"`
+	Corrected int            `json:"corrected"`
+}
+
+// Reconcile compares the bets of the games finished in [from, to) with the transactions
+// the outbox sent to UM, matched by referenceID and txRef. An empty room checks every room.
+//
+// With correct set, a missing credit whose entry failed is retried and any other
+// difference is queued as a correction entry, once per bet and amount.
+// Bets without any outbox entry predate the outbox, they are counted and skipped.
+//
+// A transaction UM applied twice cannot be detected: the outbox holds one entry per
+// idempotency key and UM's own transactions are not read.
+func Reconcile(from, to time.Time, room string, correct bool) (ReconReport, error) {
+	report := ReconReport{From: from, To: to, Findings: []ReconFinding{}}
+
+	targets := ListRooms()
+	if room != "" {
+		r, ok := GetRoom(room)
+		if !ok {
+			return report, fmt.Errorf("room %s not found", room)
+		}
+		targets = []*Room{r}
+	}
+	for _, r := range targets {
+		if err := r.reconcile(&report, correct); err != nil {
+			return report, fmt.Errorf("room %s: %w", r.ID, err)
+		}
+	}
+	return report, nil
+}
+
+// reconcile adds the findings of the room to the report
+func (r *Room) reconcile(report *ReconReport, correct bool) error {
+	bets, err := r.Bets.ListFinished(report.From, report.To)
+	if err != nil {
+		return fmt.Errorf("load bets: %v", err)
+	}
+	if len(bets) == 0 {
+		return nil
+	}
+	report.Bets += len(bets)
+
+	// Bet IDs grow with the games, so the window's bets are one ID range
+	entries, err := outbox.ListByBets(r.ID, bets[0].ID, bets[len(bets)-1].ID)
+	if err != nil {
+		return fmt.Errorf("load outbox: %v", err)
+	}
+	byRef := make(map[string][]OutboxEntry)
+	for _, e := range entries {
+		byRef[e.TxRef] = append(byRef[e.TxRef], e)
+	}
+
+	for _, bet := range bets {
+		var betEntries []OutboxEntry
+		for _, e := range byRef[strconv.FormatInt(bet.ID, 10)] {
+			if e.ReferenceID == referenceOf(bet, e.TxType) {
+				betEntries = append(betEntries, e)
+			}
+		}
+		if len(betEntries) == 0 {
+			report.PreOutbox++
+			continue
+		}
+		if hasStatus(betEntries, OutboxUnknown) {
+			report.Unknown++
+			continue
+		}
+		if hasStatus(betEntries, OutboxPending, OutboxSending) {
+			report.InFlight++
+			continue
+		}
+		for _, f := range r.compareBet(bet, betEntries) {
+			if correct && !f.Queued {
+				if err := r.correct(bet, &f, betEntries); err != nil {
+					log.Println("reconcile >", err)
+				} else {
+					report.Corrected++
+				}
+			}
+			report.Findings = append(report.Findings, f)
+		}
+	}
+	return nil
+}
+
+// referenceOf is the referenceID a transaction of the bet is sent with
+func referenceOf(bet models.Bet, txType string) string {
+	if txType == outboxTxTypes[OutboxDebit] {
+		return strconv.FormatInt(bet.GameID, 10)
+	}
+	return "2"
+}
+
+// hasStatus tells whether a transaction of the bet is in one of the statuses
+func hasStatus(entries []OutboxEntry, statuses ...string) bool {
+	for _, e := range entries {
+		if utils.InArray(statuses, e.Status) {
+			return true
+		}
+	}
+	return false
+}
+
+// expectedAmounts returns what the bet row says UM should have recorded, per ledger
+func expectedAmounts(bet models.Bet, entries []OutboxEntry) map[string]float64 {
+	expected := map[string]float64{
+		OutboxDebit:  bet.Bet,
+		OutboxWin:    0,
+		OutboxRefund: 0,
+	}
+	// A bet whose debit failed was never placed
+	for _, e := range entries {
+		if e.Kind == OutboxDebit && e.Status == OutboxFailed {
+			bet.State = models.BetVoided
+		}
+	}
+	if bet.State == models.BetVoided {
+		expected[OutboxDebit] = 0
+		return expected
+	}
+	if bet.Payout > 0 {
+		if bet.CheckoutBy == CheckoutByRefund {
+			expected[OutboxRefund] = bet.Payout
+		} else {
+			expected[OutboxWin] = bet.Payout
+		}
+	}
+	return expected
+}
+
+// compareBet matches the confirmed transactions of a bet with its row
+func (r *Room) compareBet(bet models.Bet, entries []OutboxEntry) []ReconFinding {
+	var findings []ReconFinding
+	amounts := expectedAmounts(bet, entries)
+	for _, ledger := range reconLedgers {
+		expected := amounts[ledger]
+		f := ReconFinding{
+			Room:     r.ID,
+			GameID:   bet.GameID,
+			BetID:    bet.ID,
+			UserID:   bet.UserID,
+			Ledger:   ledger,
+			Expected: expected,
+		}
+
+		confirmed := false
+		for _, e := range entries {
+			switch {
+			case e.Kind == OutboxCorrection && e.Key == correctionKey(r.ID, bet, ledger):
+				f.Entries = append(f.Entries, e.ID)
+				f.Queued = e.Status != OutboxDone
+				if e.Status == OutboxDone {
+					f.Sent += correctionSign(ledger, e.TxType) * e.Amount
+				}
+			case e.Kind == ledger:
+				f.Entries = append(f.Entries, e.ID)
+				if e.Status == OutboxDone {
+					f.Sent += e.Amount
+					confirmed = true
+				}
+			}
+		}
+		f.Sent = utils.RoundToTwoDigits(f.Sent)
+		if math.Abs(f.Sent-expected) < 0.005 {
+			continue
+		}
+
+		f.Kind = ReconMismatch
+		if !confirmed && expected > 0 {
+			f.Kind = ReconMissing
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// correctionKey is the idempotency key of the single correction of a bet amount
+func correctionKey(room string, bet models.Bet, ledger string) string {
+	return OutboxKey(room, bet.GameID, bet.ID, OutboxCorrection+"-"+ledger)
+}
+
+// correctionSign tells whether a correction of UM type correctionType adds to or takes
+// from the amount of a ledger
+func correctionSign(ledger, correctionType string) float64 {
+	debit := outboxTxTypes[OutboxDebit]
+	if (ledger == OutboxDebit) == (correctionType == debit) {
+		return 1
+	}
+	return -1
+}
+
+// correct queues the fix of a finding: a failed credit is retried, anything else gets
+// a correction entry for the difference
+func (r *Room) correct(bet models.Bet, f *ReconFinding, entries []OutboxEntry) error {
+	key := correctionKey(r.ID, bet, f.Ledger)
+	for _, e := range entries {
+		if e.Key == key {
+			return fmt.Errorf("bet %d %s already corrected by entry %d", bet.ID, f.Ledger, e.ID)
+		}
+	}
+	if f.Kind == ReconMissing {
+		for _, e := range entries {
+			if e.Kind == f.Ledger && e.Status == OutboxFailed && e.Kind != OutboxDebit {
+				if _, err := retryOutbox(e.ID); err != nil {
+					return err
+				}
+				f.Queued = true
+				return nil
+			}
+		}
+	}
+
+	// The correction moves the difference in the direction of the amount
+	diff := utils.RoundToTwoDigits(f.Expected - f.Sent)
+	correctionType := outboxTxTypes[f.Ledger]
+	switch {
+	case f.Ledger == OutboxDebit && diff < 0:
+		correctionType = outboxTxTypes[OutboxRefund]
+	case f.Ledger != OutboxDebit && diff < 0:
+		correctionType = outboxTxTypes[OutboxDebit]
+	}
+	e, err := r.enqueueTx(key, OutboxCorrection, correctionType, bet, math.Abs(diff))
+	if err != nil {
+		return err
+	}
+	f.Entries = append(f.Entries, e.ID)
+	f.Queued = true
+	return nil
+}
+
+// reconcileInterval reads RECONCILE_INTERVAL_MIN, 0 turns the periodic reconciliation off
+func reconcileInterval() time.Duration {
+	v := os.Getenv("RECONCILE_INTERVAL_MIN")
+	if v == "" {
+		return defaultReconcileInterval
+	}
+	m, err := strconv.Atoi(v)
+	if err != nil || m < 0 {
+		return defaultReconcileInterval
+	}
+	return time.Duration(m) * time.Minute
+}
+
+// StartReconciler reconciles the games finished in each interval, once they are older
+// than the reconciliation lag. RECONCILE_CORRECT=1 queues corrections automatically.
+func StartReconciler() {
+	interval := reconcileInterval()
+	if interval == 0 {
+		log.Println("🧾 [reconcile] periodic reconciliation off")
+		return
+	}
+	correct := os.Getenv("RECONCILE_CORRECT") == "1"
+	log.Println("🧾 [reconcile] every", interval, "correct:", correct)
+
+	go func() {
+		to := time.Now().Add(-reconcileLag)
+		from := to.Add(-interval)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			report, err := Reconcile(from, to, "", correct)
+			if err != nil {
+				log.Println("🧾 [reconcile]", err)
+			} else {
+				logReconReport(report)
+				from = to
+			}
+			<-ticker.C
+			to = time.Now().Add(-reconcileLag)
+		}
+	}()
+}
+
+func logReconReport(report ReconReport) {
+	log.Printf("🧾 [reconcile] %s - %s: %d bets, %d in flight, %d unknown, %d before the outbox, %d findings, %d corrected",
+		report.From.UTC().Format(time.RFC3339), report.To.UTC().Format(time.RFC3339),
+		report.Bets, report.InFlight, report.Unknown, report.PreOutbox, len(report.Findings), report.Corrected)
+	for _, f := range report.Findings {
+		log.Printf("🧾 [reconcile] %s %s bet %d (game %d, user %d) %s expected %.2f sent %.2f queued %v",
+			f.Kind, f.Room, f.BetID, f.GameID, f.UserID, f.Ledger, f.Expected, f.Sent, f.Queued)
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/he"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
+)
+
+// Game statuses, set when a game leaves is_live
+const (
+	GameStatusFinished = models.GameFinished
+	GameStatusVoided   = models.GameVoided
+)
+
+// Recovery checkout marks on bets settled at boot
+const (
+	CheckoutByRecovery = "Recovery"
+	CheckoutByRefund   = "Refund"
+	CheckoutByVoid     = "Void"
+)
+
+// Recover closes the rounds a previous process left is_live=1, before any new round starts.
+//
+// Policy, per unfinished game:
+//   - Paid bets (payout > 0) are kept as they are.
+//   - Unpaid bets, whose credit the round gave up on, are credited their payout now.
+//   - Bets whose debit is missing from the outbox or not done were never placed: they
+//     are voided, nothing is refunded. An unknown debit is left to an admin.
+//   - Bets with a win in the outbox are won with that payout, whether or not it reached UM.
+//   - Crash point not fixed yet (the process died while betting was open or before the
+//     client seed was set): the game is voided and every unpaid stake is refunded.
+//   - Crash point fixed: the game is finished. Unpaid bets whose auto-cashout target is at
+//     or below the recorded crash point are paid at their target, capped by the round max
+//     win. Every other unpaid bet is:
+//     lost when the round was stored as crashed (EndAt set), its seed is already revealed
+//     and the process died settling it;
+//     refunded otherwise, since the player lost the chance to cash out.
+//
+// Voided and finished games are revealed like any other finished game.
+// Credits go through the outbox under the bet's idempotency key, so running recovery
+// again never pays a bet twice.
+func (r *Room) Recover() {
+	games, err := r.Games.ListUnfinished()
+	if err != nil {
+		log.Fatalln("RECOVERY:", err)
+	}
+	for _, game := range games {
+		r.recoverGame(game)
+	}
+}
+
+func (r *Room) recoverGame(game models.Game) {
+	bets, err := r.Bets.ListByGame(game.ID)
+	if err != nil {
+		log.Fatalln("RECOVERY:", err)
+	}
+
+	maxWin := 0.
+	if game.Risk != nil {
+		maxWin = game.Risk.Policy.MaxWin
+	}
+	status := GameStatusVoided
+	if game.CrashAt > 0 {
+		status = GameStatusFinished
+	}
+	crashed := status == GameStatusFinished && !game.EndAt.IsZero()
+
+	tracker := he.NewTracker()
+	refunded, paid, lost, voided := 0, 0, 0, 0
+	for _, bet := range bets {
+		// The credit of an unpaid bet never reached the outbox, record it now
+		if bet.State == models.BetUnpaid {
+			tracker.AddIncome(bet.Bet)
+			tracker.AddExpense(bet.Payout)
+			bet.State = models.BetWon
+			r.recoveryPayout(bet, OutboxWin)
+			paid++
+			continue
+		}
+		if bet.Payout > 0 {
+			tracker.AddIncome(bet.Bet)
+			tracker.AddExpense(bet.Payout)
+			continue
+		}
+
+		debit, debited, err := outbox.Find(OutboxKey(r.ID, game.ID, bet.ID, OutboxDebit))
+		if err != nil {
+			log.Fatalln("RECOVERY:", err)
+		}
+		if !debited || debit.Status != OutboxDone {
+			bet.State = models.BetVoided
+			bet.CheckoutBy = CheckoutByVoid
+			r.storeRecovered(bet)
+			voided++
+			continue
+		}
+		tracker.AddIncome(bet.Bet)
+
+		win, won, err := outbox.Find(OutboxKey(r.ID, game.ID, bet.ID, OutboxWin))
+		if err != nil {
+			log.Fatalln("RECOVERY:", err)
+		}
+		if won {
+			bet.Payout = win.Amount
+			bet.State = models.BetWon
+			bet.CheckoutBy = CheckoutByRecovery
+			bet.CheckoutOn = utils.RoundToTwoDigits(win.Amount / bet.Bet)
+			r.recoveryPayout(bet, OutboxWin)
+			paid++
+		} else if status == GameStatusFinished && bet.Multiplier <= game.CrashAt {
+			bet.Payout = utils.RoundToTwoDigits(bet.Bet * bet.Multiplier)
+			if maxWin > 0 && bet.Payout > maxWin {
+				bet.Payout = maxWin
+			}
+			bet.State = models.BetWon
+			bet.CheckoutBy = CheckoutByRecovery
+			bet.CheckoutOn = bet.Multiplier
+			r.recoveryPayout(bet, OutboxWin)
+			paid++
+		} else if crashed {
+			bet.State = models.BetLost
+			r.storeRecovered(bet)
+			lost++
+		} else {
+			bet.Payout = bet.Bet
+			bet.State = models.BetRefunded
+			bet.CheckoutBy = CheckoutByRefund
+			bet.CheckoutOn = 0
+			r.recoveryPayout(bet, OutboxRefund)
+			refunded++
+		}
+		tracker.AddExpense(bet.Payout)
+	}
+
+	game.Status = status
+	if !crashed {
+		game.EndAt = time.Now().UTC()
+	}
+	if err := r.Games.Finish(game, nil, tracker); err != nil {
+		log.Fatalln("GRPC_ERROR game", game.ID, err)
+	}
+
+	log.Printf("Room %s game %d recovered as %s: %d bets, %d paid, %d lost, %d refunded, %d voided",
+		r.ID, game.ID, status, len(bets), paid, lost, refunded, voided)
+}
+
+// recoveryPayout records the credit of a recovered bet and stores the bet. Storage failures
+// stop the boot so no bet is skipped, UM failures are retried by the outbox worker.
+func (r *Room) recoveryPayout(bet models.Bet, kind string) {
+	credit, err := r.enqueue(kind, bet, bet.Payout)
+	if err != nil {
+		log.Fatalln("RECOVERY:", err)
+	}
+	r.storeRecovered(bet)
+	sendOutbox(credit)
+}
+
+// storeRecovered updates the row of a recovered bet
+func (r *Room) storeRecovered(bet models.Bet) {
+	if err := r.Bets.Update(bet); err != nil {
+		log.Fatalln("GRPC_ERROR bet", bet.ID, err)
+	}
+}
+
+// lastGameID returns the highest game ID of the room, 0 on an empty table
+func (r *Room) lastGameID() int64 {
+	id, err := r.Games.LastID()
+	if err != nil {
+		log.Fatalln("DB_DATA:", err)
+	}
+	return id
+}
+
+// loadHistory fills the crash history with the last finished games
+func (r *Room) loadHistory() {
+	games, err := r.Games.ListFinished(r.History.size)
+	if err != nil {
+		log.Println("loadHistory:", err)
+		return
+	}
+	for i := len(games) - 1; i >= 0; i-- {
+		game := games[i]
+		if game.Status == GameStatusVoided {
+			continue
+		}
+		r.History.Add(game.CrashAt)
+	}
+}
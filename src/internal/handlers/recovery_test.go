@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/fakeum"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+)
+
+func TestRecoverVoidsBetsWithoutDebit(t *testing.T) {
+	_, um := startBackends(t)
+	um.AddUser(fakeum.User{ID: 7, Balance: 100})
+	r := DefaultRoom()
+
+	gameID, err := r.Games.Insert(models.Game{Room: r.ID, ServerSeedHash: "hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The process died between the insert and the debit entry
+	lost := storedBet(t, r, models.Bet{Room: r.ID, UserID: 7, GameID: gameID, Bet: 50, Multiplier: 2})
+	placed := storedBet(t, r, models.Bet{Room: r.ID, UserID: 7, GameID: gameID, Bet: 30, Multiplier: 2})
+	debit, err := r.enqueue(OutboxDebit, placed, placed.Bet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deliverOutbox(debit); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Recover()
+
+	if got := balance(t, um, 7); got != 100 {
+		t.Errorf("balance after recovery = %.2f, want 100.00", got)
+	}
+	want := map[int64]string{lost.ID: models.BetVoided, placed.ID: models.BetRefunded}
+	for id, state := range want {
+		bet, err := r.Bets.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bet.State != state {
+			t.Errorf("bet %d state = %q, want %q", id, bet.State, state)
+		}
+	}
+	if _, found, _ := outbox.Find(OutboxKey(r.ID, gameID, lost.ID, OutboxRefund)); found {
+		t.Errorf("bet %d without debit has a refund entry", lost.ID)
+	}
+}
+
+func TestRecoverSettlesUnpaidBetsByCrash(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		crashed bool // stored with EndAt before the seed was revealed
+		state   string
+		balance float64
+	}{
+		{"died while running", false, models.BetRefunded, 100 - 10 + 15 - 20 + 20},
+		{"died after the crash", true, models.BetLost, 100 - 10 + 15 - 20},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, um := startBackends(t)
+			um.AddUser(fakeum.User{ID: 7, Balance: 100})
+			r := DefaultRoom()
+
+			game := models.Game{Room: r.ID, ServerSeedHash: "hash", CrashAt: 2}
+			id, err := r.Games.Insert(game)
+			if err != nil {
+				t.Fatal(err)
+			}
+			game.ID = id
+			if tc.crashed {
+				game.EndAt = time.Now().UTC().Truncate(time.Second)
+			}
+			if err := r.Games.Update(game, true); err != nil {
+				t.Fatal(err)
+			}
+
+			// placedBet stores a bet whose stake UM took
+			placedBet := func(stake, target float64) models.Bet {
+				bet := storedBet(t, r, models.Bet{Room: r.ID, UserID: 7, GameID: id, Bet: stake, Multiplier: target})
+				debit, err := r.enqueue(OutboxDebit, bet, bet.Bet)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := deliverOutbox(debit); err != nil {
+					t.Fatal(err)
+				}
+				return bet
+			}
+			reached := placedBet(10, 1.5)
+			missed := placedBet(20, 3)
+
+			r.Recover()
+
+			if got := balance(t, um, 7); got != tc.balance {
+				t.Errorf("balance after recovery = %.2f, want %.2f", got, tc.balance)
+			}
+			if bet, _ := r.Bets.Get(reached.ID); bet.State != models.BetWon || bet.Payout != 15 {
+				t.Errorf("bet below the crash stored %s paid %.2f, want won 15.00", bet.State, bet.Payout)
+			}
+			bet, _ := r.Bets.Get(missed.ID)
+			if bet.State != tc.state {
+				t.Errorf("bet above the crash stored %s, want %s", bet.State, tc.state)
+			}
+			_, refunded, _ := outbox.Find(OutboxKey(r.ID, id, missed.ID, OutboxRefund))
+			if refunded != (tc.state == models.BetRefunded) {
+				t.Errorf("bet above the crash has a refund entry: %v", refunded)
+			}
+
+			stored, live, err := r.Games.Get(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if live || stored.Status != GameStatusFinished {
+				t.Errorf("game stored live=%v status %q", live, stored.Status)
+			}
+			if tc.crashed && !stored.EndAt.Equal(game.EndAt) {
+				t.Errorf("game ended at %s, want the stored crash time %s", stored.EndAt, game.EndAt)
+			}
+		})
+	}
+}
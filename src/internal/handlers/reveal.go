@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/provablyfair"
+)
+
+// CrashReveals keeps the last N revealed server seeds
+type CrashReveals struct {
+	data []models.RoundReveal
+	size int
+	mu   sync.Mutex
+}
+
+// NewCrashReveals Constructor
+func NewCrashReveals(limit int) *CrashReveals {
+	return &CrashReveals{
+		data: make([]models.RoundReveal, 0, limit),
+		size: limit,
+	}
+}
+
+// Add stores a reveal, dropping the oldest one when full
+func (r *CrashReveals) Add(reveal models.RoundReveal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.data) >= r.size {
+		r.data = r.data[1:]
+	}
+	r.data = append(r.data, reveal)
+}
+
+// GetAll returns a snapshot of stored reveals
+func (r *CrashReveals) GetAll() []models.RoundReveal {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]models.RoundReveal, len(r.data))
+	copy(result, r.data)
+	return result
+}
+
+// revealRound publishes the server seed of a crashed round.
+// It must only be called once the round reached StateCrashed.
+func (r *Room) revealRound(game models.Game) {
+	reveal := models.RoundReveal{
+		ID:             game.ID,
+		ServerSeed:     game.ServerSeed,
+		ServerSeedHash: game.ServerSeedHash,
+		ClientSeed:     game.ClientSeed,
+		Nonce:          game.Nonce,
+		Profile:        game.Profile,
+		ProfileVersion: game.ProfileVersion,
+		CrashAt:        game.CrashAt,
+		RevealedAt:     time.Now().UTC(),
+	}
+	if !provablyfair.VerifyServerSeed(reveal.ServerSeed, reveal.ServerSeedHash) {
+		// Should never happen, the commitment is built from the same seed
+		log.Printf("Game %d seed does not match its commitment", game.ID)
+		return
+	}
+	r.Reveals.Add(reveal)
+	r.Emit("roundRevealed", reveal)
+}
+
+// GetReveals API handler for clients
+func GetReveals(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return resR, rErr
+	}
+
+	// Success
+	resR.Type = "getReveals"
+	resR.Data = room.Reveals.GetAll()
+	return resR, errR
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/risk"
+)
+
+// GetRiskPolicy API handler publishing the risk levers and the current round limits
+func GetRiskPolicy(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return resR, rErr
+	}
+	current := room.Engine.Live().Limits
+
+	// Tiers as applied in this room
+	tiers := make(map[string]risk.Policy, len(risk.Tiers))
+	for name, p := range risk.Tiers {
+		tiers[name] = p.Scale(room.LimitScale)
+	}
+
+	// Success
+	resR.Type = "getRiskPolicy"
+	resR.Data = map[string]interface{}{
+		"current": current,
+		"tiers":   tiers,
+		"thresholds": map[string]float64{
+			"reducedBelowHE":   risk.ReducedBelowHE,
+			"defensiveBelowHE": risk.DefensiveBelowHE,
+		},
+	}
+	return resR, errR
+}
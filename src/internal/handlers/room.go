@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/engine"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/events"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/repository"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/validate"
+)
+
+// DefaultRoomID is the room of the single-room setup, used when no rooms config is loaded
+const DefaultRoomID = "main"
+
+// RoomConfig is the published setup of a crash room.
+// Every room debits and credits the one UM balance of the player, the UM transaction API
+// has no currency, so rooms differ by their tables, limits and timings.
+type RoomConfig struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	TablePrefix string       `json:"tablePrefix"` // tables are <prefix>_games and <prefix>_bets, see migrations/
+	LimitScale  float64      `json:"limitScale"`  // multiplies the amount limits of every risk tier
+	Preset      string       `json:"preset"`      // timing preset, standard when empty
+	Timings     PhaseTimings `json:"timings"`     // non-zero fields override the preset
+}
+
+// RoomsConfig is the rooms config file, the first room is the default one
+type RoomsConfig struct {
+	Rooms []RoomConfig `json:"rooms"`
+}
+
+// Room runs its own rounds with its own engine, tables and limits
+type Room struct {
+	RoomConfig
+	Engine      *engine.GameEngine
+	History     *CrashHistory
+	Leaderboard *CrashLeaderboard
+	Reveals     *CrashReveals
+	Games       repository.GameRepository
+	Bets        repository.BetRepository
+
+	timingsMu      sync.Mutex
+	timings        PhaseTimings
+	pendingTimings *PhaseTimings
+
+	schedulerMu sync.Mutex
+	scheduler   SchedulerState
+	wake        chan struct{} // wakes the round loop after a scheduler change
+
+	seedMu         sync.Mutex
+	nextSeed       *roundSeed // drawn a round ahead, its hash is published before the client seed
+	nextClientSeed string     // fixed by the bets of the round before
+
+	payouts  sync.WaitGroup // auto-cashouts in flight
+	stop     chan struct{}  // closed to drain the room
+	stopOnce sync.Once
+	drained  chan struct{} // closed once the last round is stored
+}
+
+var (
+	roomsMu     sync.RWMutex
+	rooms       = map[string]*Room{}
+	roomOrder   []string
+	defaultRoom string
+)
+
+func init() {
+	setRooms([]RoomConfig{defaultRoomConfig()})
+}
+
+// defaultRoomConfig is the original single room on the g2 tables
+func defaultRoomConfig() RoomConfig {
+	return RoomConfig{
+		ID:          DefaultRoomID,
+		Name:        "Crash",
+		TablePrefix: "g2",
+		LimitScale:  1,
+		Preset:      PresetStandard,
+	}
+}
+
+// NewRoom creates a room with an idle engine on its Core tables, the config timings
+// must be valid
+func NewRoom(cfg RoomConfig) *Room {
+	timings, err := resolveTimings(cfg.Preset, cfg.Timings)
+	if err != nil {
+		log.Fatalf("room %s: %v", cfg.ID, err)
+	}
+	r := &Room{
+		RoomConfig:  cfg,
+		Engine:      engine.New(),
+		History:     NewCrashHistory(50),
+		Leaderboard: NewCrashLeaderboard(cfg.ID, 20),
+		Reveals:     NewCrashReveals(50),
+		timings:     timings,
+		scheduler:   SchedulerState{State: SchedulerRunning, Since: time.Now().UTC()},
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		drained:     make(chan struct{}),
+	}
+	r.Games = repository.NewCoreGames(r.GamesTable(), r.BetsTable())
+	r.Bets = repository.NewCoreBets(r.BetsTable(), r.GamesTable())
+	return r
+}
+
+// LoadRooms reads the rooms config, it must run before StartRooms.
+// On error the single default room is used.
+func LoadRooms(path string) error {
+	list, err := readRooms(path)
+	if err != nil {
+		list = []RoomConfig{defaultRoomConfig()}
+	}
+	setRooms(list)
+	return err
+}
+
+func readRooms(path string) ([]RoomConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// Unknown fields are refused so a setting G2 does not support, such as a currency, is
+	// never taken as applied
+	var cfg RoomsConfig
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid rooms config: %w", err)
+	}
+	if len(cfg.Rooms) == 0 {
+		return nil, fmt.Errorf("rooms config has no rooms")
+	}
+	seen := make(map[string]bool, len(cfg.Rooms))
+	for i, r := range cfg.Rooms {
+		if r.ID == "" || r.TablePrefix == "" {
+			return nil, fmt.Errorf("room #%d needs an id and a tablePrefix", i+1)
+		}
+		if seen[r.ID] {
+			return nil, fmt.Errorf("room %s defined twice", r.ID)
+		}
+		seen[r.ID] = true
+		if _, err := resolveTimings(r.Preset, r.Timings); err != nil {
+			return nil, fmt.Errorf("room %s: %w", r.ID, err)
+		}
+		if r.LimitScale <= 0 {
+			cfg.Rooms[i].LimitScale = 1
+		}
+	}
+	return cfg.Rooms, nil
+}
+
+func setRooms(list []RoomConfig) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	rooms = make(map[string]*Room, len(list))
+	roomOrder = roomOrder[:0]
+	for _, cfg := range list {
+		rooms[cfg.ID] = NewRoom(cfg)
+		roomOrder = append(roomOrder, cfg.ID)
+	}
+	defaultRoom = list[0].ID
+}
+
+// StartRooms recovers unfinished games, then starts the round loop of every room
+// from the last game in its table
+func StartRooms() {
+	for _, room := range ListRooms() {
+		room.Recover()
+		room.loadHistory()
+		last := room.lastGameID()
+		log.Printf("🎰 [room] %s (%s_games) from game %d", room.ID, room.TablePrefix, last)
+		go room.NextGame(last + 1)
+	}
+}
+
+// GetRoom returns a room by ID, an empty ID is the default room
+func GetRoom(id string) (*Room, bool) {
+	roomsMu.RLock()
+	defer roomsMu.RUnlock()
+	if id == "" {
+		id = defaultRoom
+	}
+	room, ok := rooms[id]
+	return room, ok
+}
+
+// DefaultRoom returns the room of clients that did not pick one
+func DefaultRoom() *Room {
+	room, _ := GetRoom("")
+	return room
+}
+
+// ListRooms returns the rooms in config order
+func ListRooms() []*Room {
+	roomsMu.RLock()
+	defer roomsMu.RUnlock()
+	list := make([]*Room, 0, len(roomOrder))
+	for _, id := range roomOrder {
+		list = append(list, rooms[id])
+	}
+	return list
+}
+
+// roomFromData resolves the optional "room" field of a request
+func roomFromData(data map[string]interface{}) (*Room, models.HandlerError, bool) {
+	var errR models.HandlerError
+
+	id := ""
+	if _, exists := data["room"]; exists {
+		var (
+			vErr models.HandlerError
+			ok   bool
+		)
+		id, vErr, ok = validate.RequireString(data, "room", true)
+		if !ok {
+			return nil, vErr, false
+		}
+	}
+	room, ok := GetRoom(id)
+	if !ok {
+		errR.Type = "ROOM_NOT_FOUND"
+		errR.Code = 8014
+		errR.Data = map[string]interface{}{
+			"room": id,
+		}
+		return nil, errR, false
+	}
+	return room, errR, true
+}
+
+// GamesTable is the games table of the room
+func (r *Room) GamesTable() string {
+	return r.TablePrefix + "_games"
+}
+
+// BetsTable is the bets table of the room
+func (r *Room) BetsTable() string {
+	return r.TablePrefix + "_bets"
+}
+
+// Emit broadcasts an event to the clients of the room
+func (r *Room) Emit(eventType string, data interface{}) {
+	events.EmitRoom(r.ID, eventType, data)
+}
+
+// GetRooms API handler listing the rooms
+func GetRooms(_ map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	list := make([]map[string]interface{}, 0)
+	for _, room := range ListRooms() {
+		live := room.Engine.Live()
+		list = append(list, map[string]interface{}{
+			"config":     room.RoomConfig,
+			"gameID":     live.ID,
+			"gameState":  live.GameState,
+			"multiplier": live.Multiplier,
+			"limits":     live.Limits,
+			"timings":    room.Timings(),
+			"scheduler":  room.Scheduler(),
+		})
+	}
+
+	// Success
+	resR.Type = "getRooms"
+	resR.Data = map[string]interface{}{
+		"default": DefaultRoom().ID,
+		"rooms":   list,
+	}
+	return resR, errR
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadRooms(t *testing.T) {
+	for _, path := range []string{"../../configs/crash_rooms.json", "../../configs/crash_rooms.example.json"} {
+		if _, err := readRooms(path); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{"currency", `{"rooms":[{"id":"eur","tablePrefix":"g2_eur","currency":"EUR"}]}`, `unknown field "currency"`},
+		{"no rooms", `{"rooms":[]}`, "no rooms"},
+		{"no table", `{"rooms":[{"id":"a"}]}`, "needs an id and a tablePrefix"},
+		{"twice", `{"rooms":[{"id":"a","tablePrefix":"a"},{"id":"a","tablePrefix":"b"}]}`, "defined twice"},
+		{"bad preset", `{"rooms":[{"id":"a","tablePrefix":"a","preset":"warp"}]}`, "room a"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rooms.json")
+			if err := os.WriteFile(path, []byte(tc.cfg), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := readRooms(path); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("readRooms error = %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
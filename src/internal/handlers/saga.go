@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/repository"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
+)
+
+// sagaTable is the audit trail of the AddBet saga, one row per step.
+// Its DDL is migrations/002_g2_bet_saga.sql.
+const sagaTable = "g2_bet_saga"
+
+// sagas stores the audit trail of every room
+var sagas repository.SagaRepository = repository.NewCoreSagas(sagaTable)
+
+// AddBet saga steps, the last four compensate the first three
+const (
+	SagaInsert   = "insert" // bet row stored
+	SagaDebit    = "debit"  // stake taken by UM
+	SagaXp       = "xp"     // XP granted
+	SagaPlace    = "place"  // bet live in the engine
+	SagaDelete   = "delete"
+	SagaVoid     = "void" // row kept voided, the debit outcome is unknown
+	SagaRefund   = "refund"
+	SagaXpRevert = "xp_revert"
+)
+
+// Saga step statuses
+const (
+	SagaOK     = "ok"
+	SagaFailed = "failed"
+)
+
+// betSaga places a bet step by step. A failed step compensates the completed ones,
+// latest first: the XP is reverted, the stake refunded and a row without debit deleted.
+// A row whose debit got no answer is kept voided, its debit entry waits for an admin.
+type betSaga struct {
+	room *Room
+	bet  models.Bet
+	xp   int
+	done []string
+
+	debitUnknown bool
+}
+
+func (r *Room) newBetSaga(bet models.Bet) *betSaga {
+	return &betSaga{room: r, bet: bet}
+}
+
+// ok records a completed step
+func (s *betSaga) ok(step, detail string) {
+	s.done = append(s.done, step)
+	s.record(step, SagaOK, detail)
+}
+
+// fail records a failed step and compensates the completed ones
+func (s *betSaga) fail(step string, err error) {
+	s.record(step, SagaFailed, err.Error())
+	log.Printf("AddBet > room %s bet %d %s failed: %v", s.room.ID, s.bet.ID, step, err)
+
+	for i := len(s.done) - 1; i >= 0; i-- {
+		switch s.done[i] {
+		case SagaXp:
+			s.revertXp()
+		case SagaDebit:
+			s.refund()
+		case SagaInsert:
+			// A debited row stays, refunded, for the ledger
+			switch {
+			case s.debited():
+			case s.debitUnknown:
+				s.void()
+			default:
+				s.room.deleteBet(s.bet.ID)
+				s.record(SagaDelete, SagaOK, "")
+			}
+		}
+	}
+}
+
+func (s *betSaga) debited() bool {
+	return utils.InArray(s.done, SagaDebit)
+}
+
+// refund gives the stake back through the outbox and marks the bet row refunded
+func (s *betSaga) refund() {
+	bet := s.bet
+	bet.Payout = bet.Bet
+	bet.State = models.BetRefunded
+	bet.CheckoutBy = CheckoutByRefund
+	credit, err := s.room.enqueue(OutboxRefund, bet, bet.Payout)
+	if err != nil {
+		s.record(SagaRefund, SagaFailed, err.Error())
+		log.Printf("AddBet > room %s bet %d NOT REFUNDED: %v", s.room.ID, bet.ID, err)
+		return
+	}
+	s.room.storeBet(bet)
+	sendOutbox(credit)
+	s.record(SagaRefund, SagaOK, fmt.Sprintf("outbox %d", credit.ID))
+}
+
+// void keeps the row of a bet whose debit may have reached UM, the reconciliation
+// refunds it once the debit is resolved as applied
+func (s *betSaga) void() {
+	bet := s.bet
+	bet.State = models.BetVoided
+	bet.CheckoutBy = CheckoutByVoid
+	if !s.room.storeBet(bet) {
+		s.record(SagaVoid, SagaFailed, "bet row not updated")
+		return
+	}
+	s.record(SagaVoid, SagaOK, "")
+}
+
+// revertXp takes the granted XP back, UM applies a negative amount as a revert
+func (s *betSaga) revertXp() {
+	res, err := utils.AddXp(int(s.bet.UserID), -s.xp, "Revert Bet", "G2")
+	if err == nil {
+		if errCode, status, errType := utils.SafeExtractErrorStatus(res); status != 1 {
+			err = fmt.Errorf("UM %s:%d", errType, errCode)
+		}
+	}
+	if err != nil {
+		s.record(SagaXpRevert, SagaFailed, err.Error())
+		log.Printf("AddBet > room %s bet %d XP not reverted: %v", s.room.ID, s.bet.ID, err)
+		return
+	}
+	s.record(SagaXpRevert, SagaOK, fmt.Sprintf("%d", -s.xp))
+}
+
+// record stores a step in the audit trail, a failure is only logged
+func (s *betSaga) record(step, status, detail string) {
+	err := sagas.Record(repository.SagaStep{
+		Room:   s.room.ID,
+		GameID: s.bet.GameID,
+		BetID:  s.bet.ID,
+		UserID: s.bet.UserID,
+		Step:   step,
+		Status: status,
+		Detail: detail,
+	})
+	if err != nil {
+		log.Printf("AddBet > audit of bet %d %s %s not stored: %v", s.bet.ID, step, status, err)
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/validate"
+)
+
+// Scheduler states of a room
+const (
+	SchedulerRunning     = "running"
+	SchedulerPausing     = "pausing" // current round finishes, then no new round
+	SchedulerPaused      = "paused"
+	SchedulerMaintenance = "maintenance" // paused with a message for players
+)
+
+// SchedulerState tells whether a room starts new rounds
+type SchedulerState struct {
+	State   string    `json:"state"`
+	Message string    `json:"message,omitempty"`
+	Since   time.Time `json:"since"`
+}
+
+// Scheduler returns the scheduler state of the room
+func (r *Room) Scheduler() SchedulerState {
+	r.schedulerMu.Lock()
+	defer r.schedulerMu.Unlock()
+	return r.scheduler
+}
+
+// setScheduler changes the state, wakes a paused loop and broadcasts the new state
+func (r *Room) setScheduler(state, message string) SchedulerState {
+	r.schedulerMu.Lock()
+	r.scheduler = SchedulerState{State: state, Message: message, Since: time.Now().UTC()}
+	st := r.scheduler
+	r.schedulerMu.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+
+	log.Printf("Room %s scheduler %s %s", r.ID, st.State, st.Message)
+	r.Emit("scheduler", st)
+	if st.State == SchedulerMaintenance {
+		r.Emit("maintenance", map[string]interface{}{
+			"reason":  "maintenance",
+			"message": st.Message,
+			"at":      st.Since.Format(time.RFC3339),
+		})
+	}
+	return st
+}
+
+// waitWhilePaused holds the round loop between rounds until the room is resumed.
+// It returns false when the room is drained meanwhile.
+func (r *Room) waitWhilePaused() bool {
+	for {
+		r.schedulerMu.Lock()
+		if r.scheduler.State == SchedulerPausing {
+			r.scheduler.State = SchedulerPaused
+			r.scheduler.Since = time.Now().UTC()
+			st := r.scheduler
+			r.schedulerMu.Unlock()
+			r.Emit("scheduler", st)
+			continue
+		}
+		running := r.scheduler.State == SchedulerRunning
+		r.schedulerMu.Unlock()
+
+		if running {
+			return true
+		}
+		select {
+		case <-r.wake:
+		case <-r.stop:
+			return false
+		}
+	}
+}
+
+// schedulerRooms resolves the rooms of a scheduler request, all rooms when "all" is true
+func schedulerRooms(data map[string]interface{}) ([]*Room, models.HandlerError, bool) {
+	if _, exists := data["all"]; exists {
+		all, vErr, ok := validate.RequireBool(data, "all")
+		if !ok {
+			return nil, vErr, false
+		}
+		if all {
+			return ListRooms(), vErr, true
+		}
+	}
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return nil, rErr, false
+	}
+	return []*Room{room}, rErr, true
+}
+
+// changeScheduler is the common part of the scheduler admin handlers
+func changeScheduler(data map[string]interface{}, resType, state string, withMessage bool) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	if aErr, ok := requireAdmin(data); !ok {
+		return resR, aErr
+	}
+	targets, rErr, ok := schedulerRooms(data)
+	if !ok {
+		return resR, rErr
+	}
+	message := ""
+	if withMessage {
+		var vErr models.HandlerError
+		message, vErr, ok = validate.RequireString(data, "message", false)
+		if !ok {
+			return resR, vErr
+		}
+	}
+
+	states := make(map[string]SchedulerState, len(targets))
+	for _, room := range targets {
+		states[room.ID] = room.setScheduler(state, message)
+	}
+
+	// Success
+	resR.Type = resType
+	resR.Data = states
+	return resR, errR
+}
+
+// PauseRooms admin API handler, rooms stop after the current round
+func PauseRooms(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	return changeScheduler(data, "pauseRooms", SchedulerPausing, false)
+}
+
+// ResumeRooms admin API handler, paused rooms start a new round
+func ResumeRooms(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	return changeScheduler(data, "resumeRooms", SchedulerRunning, false)
+}
+
+// SetMaintenance admin API handler, rooms stop after the current round and show the message
+func SetMaintenance(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	return changeScheduler(data, "setMaintenance", SchedulerMaintenance, true)
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/provablyfair"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/validate"
+)
+
+const (
+	SeedModeRandom = "random"
+	SeedModeChain  = "chain"
+)
+
+const defaultSeedChainLength = 100000
+
+var (
+	seedMode      = SeedModeRandom
+	seedChain     *provablyfair.SeedChain
+	seedChainPath = "data/seed_chain.json"
+	seedChainMu   sync.RWMutex
+)
+
+// InitSeedSource picks the server seed source from SEED_MODE.
+// In chain mode the chain at SEED_CHAIN_PATH is loaded, or generated when missing. The file
+// holds the secret origin, it must be on a persistent volume only the app user can read
+// (the image mounts /app/data for it): a lost chain breaks the published terminal hash.
+func InitSeedSource() {
+	if os.Getenv("SEED_MODE") != SeedModeChain {
+		log.Println("🎲 [seed] random server seed per round")
+		return
+	}
+	seedMode = SeedModeChain
+	if p := os.Getenv("SEED_CHAIN_PATH"); p != "" {
+		seedChainPath = p
+	}
+
+	chain, err := provablyfair.LoadSeedChain(seedChainPath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Fatalln("SEED_CHAIN:", err)
+		}
+		chain, err = provablyfair.NewSeedChain(seedChainLength(), seedChainPath)
+		if err != nil {
+			log.Fatalln("SEED_CHAIN:", err)
+		}
+	}
+	seedChain = chain
+	status := chain.Status()
+	log.Printf("⛓ [seed] chain %s, %d/%d used", status.TerminalHash, status.Used, status.Length)
+}
+
+// seedChainLength reads SEED_CHAIN_LENGTH with a sane default, capped to the chain maximum
+func seedChainLength() int {
+	n, err := strconv.Atoi(os.Getenv("SEED_CHAIN_LENGTH"))
+	if err != nil || n < 1 {
+		return defaultSeedChainLength
+	}
+	return min(n, provablyfair.MaxSeedChainLength)
+}
+
+// nextServerSeed returns the seed for the next round from the configured source.
+// An exhausted chain is rotated automatically.
+func nextServerSeed() (seed string, seedHash string, chainHash string, chainIndex int) {
+	if seedMode != SeedModeChain {
+		seed, seedHash = provablyfair.GenerateServerSeed()
+		return seed, seedHash, "", 0
+	}
+
+	seedChainMu.Lock()
+	defer seedChainMu.Unlock()
+
+	seed, seedHash, chainIndex, err := seedChain.Next()
+	if errors.Is(err, provablyfair.ErrChainExhausted) {
+		log.Println("⛓ [seed] chain exhausted, rotating")
+		chain, rErr := provablyfair.NewSeedChain(seedChain.Length, seedChainPath)
+		if rErr != nil {
+			log.Fatalln("SEED_CHAIN:", rErr)
+		}
+		seedChain = chain
+		seed, seedHash, chainIndex, err = seedChain.Next()
+	}
+	if err != nil {
+		log.Fatalln("SEED_CHAIN:", err)
+	}
+	return seed, seedHash, seedChain.TerminalHash, chainIndex
+}
+
+// GetSeedChain API handler for clients
+func GetSeedChain(_ map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	resR.Type = "getSeedChain"
+	if seedMode != SeedModeChain {
+		resR.Data = map[string]interface{}{
+			"mode": seedMode,
+		}
+		return resR, errR
+	}
+
+	seedChainMu.RLock()
+	status := seedChain.Status()
+	seedChainMu.RUnlock()
+
+	// Success
+	resR.Data = map[string]interface{}{
+		"mode":         seedMode,
+		"terminalHash": status.TerminalHash,
+		"length":       status.Length,
+		"createdAt":    status.CreatedAt,
+	}
+	return resR, errR
+}
+
+// GetSeedChainStatus Admin handler reporting chain usage
+func GetSeedChainStatus(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	if aErr, ok := requireAdmin(data); !ok {
+		return resR, aErr
+	}
+	if seedMode != SeedModeChain {
+		errR.Type = "SEED_CHAIN_DISABLED"
+		errR.Code = 8010
+		return resR, errR
+	}
+
+	seedChainMu.RLock()
+	status := seedChain.Status()
+	seedChainMu.RUnlock()
+
+	// Success
+	resR.Type = "getSeedChainStatus"
+	resR.Data = status
+	return resR, errR
+}
+
+// RotateSeedChain Admin handler replacing the current chain with a fresh one
+func RotateSeedChain(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	if aErr, ok := requireAdmin(data); !ok {
+		return resR, aErr
+	}
+	if seedMode != SeedModeChain {
+		errR.Type = "SEED_CHAIN_DISABLED"
+		errR.Code = 8010
+		return resR, errR
+	}
+
+	seedChainMu.RLock()
+	length := seedChain.Length
+	seedChainMu.RUnlock()
+	if _, exists := data["length"]; exists {
+		n, vErr, ok := validate.RequireInt(data, "length")
+		if !ok {
+			return resR, vErr
+		}
+		if n < 1 || n > provablyfair.MaxSeedChainLength {
+			errR.Type = "INVALID_TYPE_OR_FORMAT"
+			errR.Code = 5003
+			errR.Data = map[string]any{
+				"fieldName": "length",
+				"fieldType": "int",
+				"max":       provablyfair.MaxSeedChainLength,
+			}
+			return resR, errR
+		}
+		length = int(n)
+	}
+
+	// Built before taking the lock, rounds keep drawing seeds from the current chain meanwhile
+	chain, err := provablyfair.GenerateSeedChain(length, seedChainPath)
+	if err != nil {
+		log.Println("RotateSeedChain:", err)
+		errR.Type = "SEED_CHAIN_ERROR"
+		errR.Code = 8011
+		return resR, errR
+	}
+
+	seedChainMu.Lock()
+	defer seedChainMu.Unlock()
+	if err := chain.Save(); err != nil {
+		log.Println("RotateSeedChain:", err)
+		errR.Type = "SEED_CHAIN_ERROR"
+		errR.Code = 8011
+		return resR, errR
+	}
+	old := seedChain.Status()
+	seedChain = chain
+	log.Printf("⛓ [seed] chain rotated %s -> %s", old.TerminalHash, chain.TerminalHash)
+
+	// Success
+	resR.Type = "rotateSeedChain"
+	resR.Data = map[string]interface{}{
+		"previous": old,
+		"current":  chain.Status(),
+	}
+	return resR, errR
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/events"
+)
+
+// Drain stops the room after its current round: betting closes now, the round runs to
+// its crash, auto-cashouts are settled and the game and tracker are stored.
+// It returns once the room is idle.
+func (r *Room) Drain() {
+	r.stopOnce.Do(func() { close(r.stop) })
+	<-r.drained
+}
+
+// Draining tells whether the room is shutting down
+func (r *Room) Draining() bool {
+	select {
+	case <-r.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// DrainRooms announces the maintenance and drains every room in parallel
+func DrainRooms() {
+	events.Emit("all", "maintenance", map[string]interface{}{
+		"reason": "shutdown",
+		"at":     time.Now().UTC().Format(time.RFC3339),
+	})
+
+	rooms := ListRooms()
+	done := make(chan string, len(rooms))
+	for _, room := range rooms {
+		go func(room *Room) {
+			room.Drain()
+			done <- room.ID
+		}(room)
+	}
+	for range rooms {
+		log.Printf("🛑 [room] %s drained", <-done)
+	}
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/engine"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/validate"
+)
+
+// Timing presets
+const (
+	PresetStandard = "standard"
+	PresetTurbo    = "turbo"
+)
+
+// countdownInterval is how often countdown events are sent while betting is open
+const countdownInterval = time.Second
+
+// PhaseTimings are the durations of the round phases and the curve of the running phase
+type PhaseTimings struct {
+	BettingMs   int     `json:"bettingMs"`   // bets are accepted
+	PreRunMs    int     `json:"preRunMs"`    // betting closed, crash point fixed, curve not started
+	PostCrashMs int     `json:"postCrashMs"` // crashed round stays on screen
+	CurveRate   float64 `json:"curveRate"`   // k of m(t) = e^(k·t), t in ms
+	TickMs      int     `json:"tickMs"`      // curve evaluation tick
+}
+
+// TimingPresets returns the built-in presets, the standard curve honours CRASH_CURVE_RATE and CRASH_TICK_MS
+func TimingPresets() map[string]PhaseTimings {
+	curve := engine.CurveFromEnv()
+	return map[string]PhaseTimings{
+		PresetStandard: {
+			BettingMs:   15000,
+			PreRunMs:    2000,
+			PostCrashMs: 3000,
+			CurveRate:   curve.Rate,
+			TickMs:      int(curve.Tick.Milliseconds()),
+		},
+		PresetTurbo: {
+			BettingMs:   5000,
+			PreRunMs:    1000,
+			PostCrashMs: 1500,
+			CurveRate:   curve.Rate * 2,
+			TickMs:      50,
+		},
+	}
+}
+
+// resolveTimings builds timings from a preset with the non-zero fields of override on top
+func resolveTimings(preset string, override PhaseTimings) (PhaseTimings, error) {
+	if preset == "" {
+		preset = PresetStandard
+	}
+	t, ok := TimingPresets()[preset]
+	if !ok {
+		return t, fmt.Errorf("unknown timing preset %q", preset)
+	}
+	t = t.with(override)
+	return t, t.Validate()
+}
+
+// with returns t with the non-zero fields of override on top
+func (t PhaseTimings) with(override PhaseTimings) PhaseTimings {
+	if override.BettingMs != 0 {
+		t.BettingMs = override.BettingMs
+	}
+	if override.PreRunMs != 0 {
+		t.PreRunMs = override.PreRunMs
+	}
+	if override.PostCrashMs != 0 {
+		t.PostCrashMs = override.PostCrashMs
+	}
+	if override.CurveRate != 0 {
+		t.CurveRate = override.CurveRate
+	}
+	if override.TickMs != 0 {
+		t.TickMs = override.TickMs
+	}
+	return t
+}
+
+// Validate keeps timings in a range the round loop can run with
+func (t PhaseTimings) Validate() error {
+	switch {
+	case t.BettingMs < 1000 || t.BettingMs > 120000:
+		return fmt.Errorf("bettingMs must be between 1000 and 120000")
+	case t.PreRunMs < 0 || t.PreRunMs > 30000:
+		return fmt.Errorf("preRunMs must be between 0 and 30000")
+	case t.PostCrashMs < 0 || t.PostCrashMs > 30000:
+		return fmt.Errorf("postCrashMs must be between 0 and 30000")
+	case t.CurveRate <= 0 || t.CurveRate > 0.01:
+		return fmt.Errorf("curveRate must be above 0 and at most 0.01")
+	case t.TickMs < 10 || t.TickMs > 1000:
+		return fmt.Errorf("tickMs must be between 10 and 1000")
+	}
+	return nil
+}
+
+// Betting is the betting window
+func (t PhaseTimings) Betting() time.Duration {
+	return time.Duration(t.BettingMs) * time.Millisecond
+}
+
+// PreRun is the pause between closing bets and starting the curve
+func (t PhaseTimings) PreRun() time.Duration {
+	return time.Duration(t.PreRunMs) * time.Millisecond
+}
+
+// PostCrash is the pause between the crash and the next round
+func (t PhaseTimings) PostCrash() time.Duration {
+	return time.Duration(t.PostCrashMs) * time.Millisecond
+}
+
+// Curve is the multiplier curve of the running phase
+func (t PhaseTimings) Curve() engine.Curve {
+	return engine.Curve{Rate: t.CurveRate, Tick: time.Duration(t.TickMs) * time.Millisecond}
+}
+
+// Timings returns the timings of the current round
+func (r *Room) Timings() PhaseTimings {
+	r.timingsMu.Lock()
+	defer r.timingsMu.Unlock()
+	return r.timings
+}
+
+// PendingTimings returns the timings waiting for the next round, if any
+func (r *Room) PendingTimings() *PhaseTimings {
+	r.timingsMu.Lock()
+	defer r.timingsMu.Unlock()
+	if r.pendingTimings == nil {
+		return nil
+	}
+	t := *r.pendingTimings
+	return &t
+}
+
+// ScheduleTimings stores timings applied when the next round opens
+func (r *Room) ScheduleTimings(t PhaseTimings) {
+	r.timingsMu.Lock()
+	defer r.timingsMu.Unlock()
+	r.pendingTimings = &t
+}
+
+// roundTimings applies scheduled timings and returns the timings of a new round
+func (r *Room) roundTimings() PhaseTimings {
+	r.timingsMu.Lock()
+	defer r.timingsMu.Unlock()
+	if r.pendingTimings != nil {
+		r.timings = *r.pendingTimings
+		r.pendingTimings = nil
+		log.Printf("Room %s timings changed: %+v", r.ID, r.timings)
+	}
+	return r.timings
+}
+
+// countdown broadcasts the time left to bet until the betting window closes.
+// Draining the room closes the window early.
+func (r *Room) countdown(gameID int64, window time.Duration) {
+	deadline := time.Now().Add(window)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return
+		}
+		r.Emit("countdown", map[string]interface{}{
+			"gameID":      gameID,
+			"remainingMs": remaining.Milliseconds(),
+		})
+		select {
+		case <-time.After(min(countdownInterval, remaining)):
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// GetRoomTimings API handler publishing the timings of a room
+func GetRoomTimings(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return resR, rErr
+	}
+
+	// Success
+	resR.Type = "getRoomTimings"
+	resR.Data = map[string]interface{}{
+		"room":    room.ID,
+		"current": room.Timings(),
+		"next":    room.PendingTimings(),
+		"presets": TimingPresets(),
+	}
+	return resR, errR
+}
+
+// SetRoomTimings admin API handler, the new timings apply from the next round
+func SetRoomTimings(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	if aErr, ok := requireAdmin(data); !ok {
+		return resR, aErr
+	}
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return resR, rErr
+	}
+
+	preset := ""
+	if _, exists := data["preset"]; exists {
+		var vErr models.HandlerError
+		preset, vErr, ok = validate.RequireString(data, "preset", false)
+		if !ok {
+			return resR, vErr
+		}
+	}
+
+	var override PhaseTimings
+	for field, dst := range map[string]*int{
+		"bettingMs":   &override.BettingMs,
+		"preRunMs":    &override.PreRunMs,
+		"postCrashMs": &override.PostCrashMs,
+		"tickMs":      &override.TickMs,
+	} {
+		if _, exists := data[field]; !exists {
+			continue
+		}
+		v, vErr, ok := validate.RequireInt(data, field)
+		if !ok {
+			return resR, vErr
+		}
+		*dst = int(v)
+	}
+	if _, exists := data["curveRate"]; exists {
+		v, vErr, ok := validate.RequireFloat(data, "curveRate")
+		if !ok {
+			return resR, vErr
+		}
+		override.CurveRate = v
+	}
+
+	// Without a preset, fields change the current timings
+	var (
+		timings PhaseTimings
+		err     error
+	)
+	if preset == "" {
+		timings = room.Timings().with(override)
+		err = timings.Validate()
+	} else {
+		timings, err = resolveTimings(preset, override)
+	}
+	if err != nil {
+		errR.Type = "INVALID_TIMINGS"
+		errR.Code = 8015
+		errR.Data = map[string]interface{}{
+			"error": err.Error(),
+		}
+		return resR, errR
+	}
+	room.ScheduleTimings(timings)
+
+	// Success
+	resR.Type = "setRoomTimings"
+	resR.Data = map[string]interface{}{
+		"room":    room.ID,
+		"current": room.Timings(),
+		"next":    timings,
+	}
+	return resR, errR
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/provablyfair"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/repository"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/validate"
+)
+
+// getFinishedGame loads a finished game row of the room, its seed is revealed once is_live=0
+func (r *Room) getFinishedGame(gameID int64) (*models.Game, models.HandlerError, bool) {
+	var errR models.HandlerError
+
+	game, live, err := r.Games.Get(gameID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && live) {
+		errR.Type = "GAME_NOT_FOUND"
+		errR.Code = 8012
+		return nil, errR, false
+	}
+	if err != nil {
+		log.Println("getFinishedGame >", err)
+		errR.Type = "DB_ERROR_GRPC"
+		errR.Code = 8000
+		return nil, errR, false
+	}
+	return &game, errR, true
+}
+
+// VerifyRound API handler recomputing a round from a game ID or from seeds
+func VerifyRound(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	result := map[string]interface{}{}
+
+	var (
+		serverSeed     string
+		clientSeed     string
+		nonce          int64
+		profileName    string
+		profileVersion int
+	)
+	if _, exists := data["gameID"]; exists {
+		gameID, vErr, ok := validate.RequireInt(data, "gameID")
+		if !ok {
+			return resR, vErr
+		}
+		room, rErr, ok := roomFromData(data)
+		if !ok {
+			return resR, rErr
+		}
+		game, gErr, ok := room.getFinishedGame(gameID)
+		if !ok {
+			return resR, gErr
+		}
+		serverSeed = game.ServerSeed
+		clientSeed = game.ClientSeed
+		nonce = game.Nonce
+		profileName = game.Profile
+		profileVersion = game.ProfileVersion
+
+		result["gameID"] = game.ID
+		result["storedServerSeedHash"] = game.ServerSeedHash
+		result["hashMatches"] = provablyfair.VerifyServerSeed(game.ServerSeed, game.ServerSeedHash)
+		result["storedCrashAt"] = game.CrashAt
+		if game.SeedChainHash != "" {
+			// The seed must sit at its own position, a seed reused from later rounds also reaches the hash
+			position, chainOK := provablyfair.VerifyChainSeed(game.ServerSeed, game.SeedChainHash, game.SeedChainIndex)
+			result["seedChainHash"] = game.SeedChainHash
+			result["seedChainIndex"] = game.SeedChainIndex
+			result["seedChainMatches"] = chainOK && position == game.SeedChainIndex
+		}
+	} else {
+		var (
+			vErr models.HandlerError
+			ok   bool
+		)
+		serverSeed, vErr, ok = validate.RequireString(data, "serverSeed", false)
+		if !ok {
+			return resR, vErr
+		}
+		if _, exists := data["clientSeed"]; exists {
+			clientSeed, vErr, ok = validate.RequireString(data, "clientSeed", true)
+			if !ok {
+				return resR, vErr
+			}
+		}
+		if _, exists := data["nonce"]; exists {
+			nonce, vErr, ok = validate.RequireInt(data, "nonce")
+			if !ok {
+				return resR, vErr
+			}
+		}
+		if _, exists := data["profile"]; exists {
+			profileName, vErr, ok = validate.RequireString(data, "profile", false)
+			if !ok {
+				return resR, vErr
+			}
+		}
+		if _, exists := data["profileVersion"]; exists {
+			v, vErr, ok := validate.RequireInt(data, "profileVersion")
+			if !ok {
+				return resR, vErr
+			}
+			profileVersion = int(v)
+		}
+		if _, exists := data["serverSeedHash"]; exists {
+			seedHash, vErr, ok := validate.RequireString(data, "serverSeedHash", false)
+			if !ok {
+				return resR, vErr
+			}
+			result["hashMatches"] = provablyfair.VerifyServerSeed(serverSeed, seedHash)
+		}
+	}
+
+	profile, ok := provablyfair.GetProfile(profileName, profileVersion)
+	if !ok {
+		errR.Type = "PROFILE_NOT_FOUND"
+		errR.Code = 8013
+		errR.Data = map[string]interface{}{
+			"profile":        profileName,
+			"profileVersion": profileVersion,
+		}
+		return resR, errR
+	}
+
+	verification := provablyfair.VerifyRound(profile, serverSeed, clientSeed, nonce)
+	result["verification"] = verification
+	if stored, ok := result["storedCrashAt"].(float64); ok {
+		result["crashMatches"] = stored == verification.Multiplier
+	}
+
+	// Success
+	resR.Type = "verifyRound"
+	resR.Data = result
+	return resR, errR
+}
+
+// GetCrashProfiles API handler publishing every crash point profile version
+func GetCrashProfiles(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	room, rErr, ok := roomFromData(data)
+	if !ok {
+		return resR, rErr
+	}
+
+	// Success
+	resR.Type = "getCrashProfiles"
+	resR.Data = map[string]interface{}{
+		"default":  provablyfair.DefaultProfile().Name,
+		"profiles": provablyfair.ListProfiles(),
+		"curve":    room.Timings().Curve(),
+	}
+	return resR, errR
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	errorsreg "github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/errors"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/provablyfair"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/repository"
+)
+
+func TestVerifyRoundChecksChainPosition(t *testing.T) {
+	setRooms([]RoomConfig{defaultRoomConfig()})
+	r := DefaultRoom()
+	games, bets := repository.NewMemory()
+	r.Games, r.Bets = games, bets
+
+	chain, err := provablyfair.NewSeedChain(5, filepath.Join(t.TempDir(), "chain.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, _, _ := chain.Next()
+	second, _, position, _ := chain.Next()
+
+	// finishedGame stores a finished round on seed, claiming the chain position index
+	profile := provablyfair.DefaultProfile()
+	finishedGame := func(seed string, index int) int64 {
+		game := models.Game{
+			ServerSeed:     seed,
+			ServerSeedHash: provablyfair.HashServerSeed(seed),
+			Profile:        profile.Name,
+			ProfileVersion: profile.Version,
+			SeedChainHash:  chain.TerminalHash,
+			SeedChainIndex: index,
+			Status:         GameStatusFinished,
+		}
+		id, err := games.Insert(game)
+		if err != nil {
+			t.Fatal(err)
+		}
+		game.ID, game.Nonce = id, id
+		game.CrashAt = provablyfair.VerifyRound(profile, seed, "", id).Multiplier
+		if err := games.Update(game, false); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	for _, tc := range []struct {
+		name string
+		seed string
+		idx  int
+		want bool
+	}{
+		{"own position", second, position, true},
+		{"reused earlier seed", first, position, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			id := finishedGame(tc.seed, tc.idx)
+			res, errR := VerifyRound(map[string]interface{}{"gameID": float64(id)})
+			if errR.Code != 0 || errR.Type != "" {
+				t.Fatalf("VerifyRound: %+v", errR)
+			}
+			result := res.Data.(map[string]interface{})
+			if got := result["seedChainMatches"]; got != tc.want {
+				t.Errorf("seedChainMatches = %v, want %v", got, tc.want)
+			}
+			if result["hashMatches"] != true || result["crashMatches"] != true {
+				t.Errorf("round %d result %+v", id, result)
+			}
+		})
+	}
+}
+
+func TestVerifyRoundNotFoundStatus(t *testing.T) {
+	setRooms([]RoomConfig{defaultRoomConfig()})
+	r := DefaultRoom()
+	r.Games, r.Bets = repository.NewMemory()
+
+	for _, tc := range []struct {
+		name string
+		data map[string]interface{}
+		key  string
+	}{
+		{"unknown game", map[string]interface{}{"gameID": float64(404)}, "GAME_NOT_FOUND"},
+		{"unknown profile", map[string]interface{}{"serverSeed": "seed", "profile": "nope"}, "PROFILE_NOT_FOUND"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, errR := VerifyRound(tc.data)
+			if errR.Type != tc.key {
+				t.Fatalf("VerifyRound error = %+v, want %s", errR, tc.key)
+			}
+			if got := errorsreg.HTTPStatus(errR.Code); got != http.StatusNotFound {
+				t.Errorf("%s answers HTTP %d, want %d", tc.key, got, http.StatusNotFound)
+			}
+		})
+	}
+}
@@ -1,17 +1,20 @@
 package he
 
 import (
-	"fmt"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/grpcclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
 	"log"
+	"sync"
 )
 
 // Tracker keeps track of financial stats for a single game (income, expense, ROI, HE).
+// It is safe for concurrent use.
 type Tracker struct {
 	Income  float64
 	Expense float64
 	ROI     float64
 	HE      float64
+	mu      sync.Mutex
 }
 
 // NewTracker creates and returns a new Tracker instance.
@@ -21,11 +24,15 @@ func NewTracker() *Tracker {
 
 // AddIncome increments total income (player deposits, bets, etc.).
 func (t *Tracker) AddIncome(amount float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.Income += amount
 }
 
 // AddExpense increments total expense (payouts, rewards, etc.).
 func (t *Tracker) AddExpense(amount float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.Expense += amount
 }
 
@@ -54,24 +61,36 @@ func (t *Tracker) CalHouseEdge() {
 	t.HE = (t.Income - t.Expense) / t.Income * 100
 }
 
-// Save computes ROI and HE, then persists all values to the database.
-func (t *Tracker) Save(gameTable string, gameID int) {
+// Totals computes ROI and HE, then returns them with the income and expense.
+func (t *Tracker) Totals() (income, expense, roi, houseEdge float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.calRatio()
 	t.CalHouseEdge()
+	return t.Income, t.Expense, t.ROI, t.HE
+}
 
-	query := fmt.Sprintf(
-		`UPDATE %s SET income=%.2f, expense=%.2f, roi=%.2f, he=%.2f WHERE id=%d`,
-		gameTable,
-		t.Income,
-		t.Expense,
-		t.ROI,
-		t.HE,
-		gameID,
-	)
-	log.Println(query)
-
-	grpcclient.SendQuery(query)
+// Update computes ROI and HE, then returns the update of the game row, to run alone
+// or in a transaction.
+func (t *Tracker) Update(gameTable string, gameID int) *grpcclient.UpdateQuery {
+	income, expense, roi, houseEdge := t.Totals()
+	return grpcclient.Update(gameTable).
+		Set("income", utils.RoundToTwoDigits(income)).
+		Set("expense", utils.RoundToTwoDigits(expense)).
+		Set("roi", utils.RoundToTwoDigits(roi)).
+		Set("he", utils.RoundToTwoDigits(houseEdge)).
+		Where("id = ?", gameID)
+}
 
+// Save computes ROI and HE, then persists all values to the database.
+func (t *Tracker) Save(gameTable string, gameID int) {
+	_, err := t.Update(gameTable, gameID).Exec()
+	if err != nil {
+		log.Println("Tracker.Save", gameTable, gameID, err)
+		return
+	}
+	income, expense, roi, houseEdge := t.Totals()
+	log.Printf("Tracker.Save %s %d income=%.2f expense=%.2f roi=%.2f he=%.2f", gameTable, gameID, income, expense, roi, houseEdge)
 }
 
 func _Example() {
@@ -2,20 +2,50 @@ package models
 
 import (
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/he"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/risk"
 	"time"
 )
 
 type LiveGame struct {
-	ID             int64       `json:"id"`
-	Multiplier     float64     `json:"multiplier"`
-	GameState      int         `json:"gameState"`
-	ServerSeedHash string      `json:"serverSeedHash"`
-	ServerTime     int64       `json:"serverTime"`
-	Tracker        *he.Tracker `json:"-"`
+	ID                 int64       `json:"id"`
+	Room               string      `json:"room"`
+	Multiplier         float64     `json:"multiplier"`
+	GameState          int         `json:"gameState"`
+	ServerSeedHash     string      `json:"serverSeedHash"`
+	NextServerSeedHash string      `json:"nextServerSeedHash"` // committed before this round's bets fix its client seed
+	ClientSeed         string      `json:"clientSeed"`
+	Nonce              int64       `json:"nonce"`
+	Profile            string      `json:"profile"`
+	ProfileVersion     int         `json:"profileVersion"`
+	ServerTime         int64       `json:"serverTime"`
+	StartedAt          int64       `json:"startedAt"`
+	CurveRate          float64     `json:"curveRate"`
+	Limits             risk.Policy `json:"limits"`
+	Tracker            *he.Tracker `json:"-"`
 }
 
+// Bet states. A bet is credited only on the settling to won transition, so it is paid once.
+//
+//	open -> settling -> won
+//	open -> lost (round crashed)
+//	open/settling -> refunded
+//	settling -> unpaid (its credit could not be recorded, reconciliation pays it)
+//	settling stays until its credit is recorded, a failure is retried with backoff
+//
+// A stored bet whose debit was never confirmed is voided by recovery.
+const (
+	BetOpen     = "open"
+	BetSettling = "settling"
+	BetWon      = "won"
+	BetUnpaid   = "unpaid"
+	BetLost     = "lost"
+	BetRefunded = "refunded"
+	BetVoided   = "voided"
+)
+
 type Bet struct {
 	ID          int64     `json:"id"`
+	Room        string    `json:"room,omitempty"`
 	UserID      int64     `json:"userID"`
 	DisplayName string    `json:"displayName"`
 	Avatar      string    `json:"avatar"`
@@ -24,17 +54,47 @@ type Bet struct {
 	Bet         float64   `json:"bet"`
 	Payout      float64   `json:"payout"`
 	Multiplier  float64   `json:"multiplier"`
+	ClientSeed  string    `json:"clientSeed"`
 	CheckoutOn  float64   `json:"checkoutOn"`
 	CheckoutBy  string    `json:"checkoutBy"`
+	State       string    `json:"state,omitempty"`
 	CreatedAt   time.Time `json:"createdAt"`
 }
 
+// Game statuses, set when a game leaves is_live. A voided game has no crash point, its
+// bets were refunded.
+const (
+	GameFinished = "finished"
+	GameVoided   = "voided"
+)
+
 type Game struct {
+	ID             int64          `json:"id"`
+	Room           string         `json:"room,omitempty"`
+	StartAt        time.Time      `json:"startAt"`
+	EndAt          time.Time      `json:"endAt"`
+	Multiplier     float64        `json:"multiplier"`
+	CrashAt        float64        `json:"crashAt"`
+	ServerSeedHash string         `json:"serverSeedHash"`
+	ServerSeed     string         `json:"serverSeed"`
+	ClientSeed     string         `json:"clientSeed"`
+	Nonce          int64          `json:"nonce"`
+	Profile        string         `json:"profile,omitempty"`
+	ProfileVersion int            `json:"profileVersion,omitempty"`
+	SeedChainHash  string         `json:"seedChainHash,omitempty"`
+	SeedChainIndex int            `json:"seedChainIndex,omitempty"`
+	Risk           *risk.Decision `json:"risk,omitempty"`
+	Status         string         `json:"status,omitempty"` // GameFinished or GameVoided
+}
+
+type RoundReveal struct {
 	ID             int64     `json:"id"`
-	StartAt        time.Time `json:"startAt"`
-	EndAt          time.Time `json:"endAt"`
-	Multiplier     float64   `json:"multiplier"`
-	CrashAt        float64   `json:"crashAt"`
-	ServerSeedHash string    `json:"serverSeedHash"`
 	ServerSeed     string    `json:"serverSeed"`
+	ServerSeedHash string    `json:"serverSeedHash"`
+	ClientSeed     string    `json:"clientSeed"`
+	Nonce          int64     `json:"nonce"`
+	Profile        string    `json:"profile"`
+	ProfileVersion int       `json:"profileVersion"`
+	CrashAt        float64   `json:"crashAt"`
+	RevealedAt     time.Time `json:"revealedAt"`
 }
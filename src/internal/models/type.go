@@ -16,6 +16,7 @@ type Request struct {
 	Type  string      `json:"type"`
 	ReqID int64       `json:"reqId,omitempty"`
 	Token string      `json:"token,omitempty"` // For Admin Side
+	Room  string      `json:"room,omitempty"`  // Crash room, empty is the default room
 	Data  interface{} `json:"data,omitempty"`  // present only on success
 }
 
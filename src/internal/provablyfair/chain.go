@@ -0,0 +1,180 @@
+package provablyfair
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrChainExhausted is returned when every seed of a chain was used
+var ErrChainExhausted = errors.New("seed chain exhausted")
+
+// MaxSeedChainLength caps a chain, every seed of it is kept in memory (about 100 MB at the cap)
+const MaxSeedChainLength = 1000000
+
+// SeedChain is a reverse SHA-256 hash chain of server seeds.
+// seeds[0] is the secret origin and seeds[i] = HashServerSeed(seeds[i-1]).
+// Rounds consume the chain from the end, so every revealed seed hashes to the
+// previous round's seed and, after enough steps, to the published terminal hash.
+type SeedChain struct {
+	Origin       string    `json:"origin"`
+	Length       int       `json:"length"`
+	Used         int       `json:"used"`
+	TerminalHash string    `json:"terminalHash"`
+	CreatedAt    time.Time `json:"createdAt"`
+
+	seeds []string
+	path  string
+	mu    sync.Mutex
+}
+
+// SeedChainStatus is the public view of a chain
+type SeedChainStatus struct {
+	TerminalHash string    `json:"terminalHash"`
+	Length       int       `json:"length"`
+	Used         int       `json:"used"`
+	Remaining    int       `json:"remaining"`
+	UsedPercent  float64   `json:"usedPercent"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// NewSeedChain generates a chain of length seeds and persists it to path
+func NewSeedChain(length int, path string) (*SeedChain, error) {
+	c, err := GenerateSeedChain(length, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Save(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GenerateSeedChain builds a chain of length seeds for path without persisting it
+func GenerateSeedChain(length int, path string) (*SeedChain, error) {
+	if length < 1 || length > MaxSeedChainLength {
+		return nil, fmt.Errorf("seed chain length must be between 1 and %d", MaxSeedChainLength)
+	}
+	origin, _ := GenerateServerSeed()
+	c := &SeedChain{
+		Origin:    origin,
+		Length:    length,
+		CreatedAt: time.Now().UTC(),
+		path:      path,
+	}
+	c.build()
+	return c, nil
+}
+
+// LoadSeedChain reads a persisted chain and rebuilds its seeds. The file holds the secret
+// origin, a file other users can access is refused.
+func LoadSeedChain(path string) (*SeedChain, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return nil, fmt.Errorf("seed chain %s is accessible by other users (mode %o), it must be 600", path, info.Mode().Perm())
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &SeedChain{path: path}
+	if err := json.Unmarshal(raw, c); err != nil {
+		return nil, err
+	}
+	if c.Length < 1 || c.Length > MaxSeedChainLength {
+		return nil, fmt.Errorf("seed chain length %d out of range", c.Length)
+	}
+	terminal := c.TerminalHash
+	c.build()
+	if c.TerminalHash != terminal {
+		return nil, errors.New("seed chain terminal hash mismatch")
+	}
+	return c, nil
+}
+
+// build recomputes the seeds and the terminal hash from the origin
+func (c *SeedChain) build() {
+	c.seeds = make([]string, c.Length)
+	c.seeds[0] = c.Origin
+	for i := 1; i < c.Length; i++ {
+		c.seeds[i] = HashServerSeed(c.seeds[i-1])
+	}
+	c.TerminalHash = HashServerSeed(c.seeds[c.Length-1])
+}
+
+// Save persists the chain, a generated chain replaces the stored one only once saved
+func (c *SeedChain) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.save()
+}
+
+// save persists the chain state, it must be called with the lock held
+func (c *SeedChain) save() error {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// Next returns the next unused seed, its hash and its position in the chain.
+// The cursor is persisted before the seed is handed out so a restart never reuses it.
+func (c *SeedChain) Next() (string, string, int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Used >= c.Length {
+		return "", "", 0, ErrChainExhausted
+	}
+	c.Used++
+	if err := c.save(); err != nil {
+		c.Used--
+		return "", "", 0, err
+	}
+	seed := c.seeds[c.Length-c.Used]
+	return seed, HashServerSeed(seed), c.Used, nil
+}
+
+// Status returns how much of the chain is used
+func (c *SeedChain) Status() SeedChainStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return SeedChainStatus{
+		TerminalHash: c.TerminalHash,
+		Length:       c.Length,
+		Used:         c.Used,
+		Remaining:    c.Length - c.Used,
+		UsedPercent:  math.Round(float64(c.Used)/float64(c.Length)*10000) / 100,
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+// VerifyChainSeed hashes seed until it reaches terminalHash.
+// It returns the seed's position in the chain (1 = first round) when found within maxSteps.
+func VerifyChainSeed(seed, terminalHash string, maxSteps int) (int, bool) {
+	current := seed
+	for i := 1; i <= maxSteps; i++ {
+		current = HashServerSeed(current)
+		if current == terminalHash {
+			return i, true
+		}
+	}
+	return 0, false
+}
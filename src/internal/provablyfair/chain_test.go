@@ -0,0 +1,95 @@
+package provablyfair
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSeedChainPositions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chain.json")
+	chain, err := NewSeedChain(4, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := chain.TerminalHash
+	for want := 1; want <= 4; want++ {
+		seed, hash, position, err := chain.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if position != want || hash != HashServerSeed(seed) {
+			t.Fatalf("round %d got position %d, hash %s", want, position, hash)
+		}
+		// Every revealed seed hashes to the seed of the round before
+		if hash != prev {
+			t.Errorf("round %d seed hashes to %s, want the previous seed %s", want, hash, prev)
+		}
+		prev = seed
+
+		if got, ok := VerifyChainSeed(seed, chain.TerminalHash, position); !ok || got != position {
+			t.Errorf("round %d seed found at %d (%v), want %d", want, got, ok, position)
+		}
+		if position > 1 {
+			if _, ok := VerifyChainSeed(seed, chain.TerminalHash, position-1); ok {
+				t.Errorf("round %d seed reached the terminal hash in fewer steps than its position", want)
+			}
+		}
+	}
+	if _, _, _, err := chain.Next(); !errors.Is(err, ErrChainExhausted) {
+		t.Errorf("fifth seed error = %v, want exhausted", err)
+	}
+	if _, ok := VerifyChainSeed(testSeed(1), chain.TerminalHash, 4); ok {
+		t.Error("a seed outside the chain verified")
+	}
+	if s := chain.Status(); s.Used != 4 || s.Remaining != 0 || s.UsedPercent != 100 {
+		t.Errorf("status = %+v, want fully used", s)
+	}
+}
+
+func TestLoadSeedChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chain.json")
+	chain, err := NewSeedChain(10, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, _, _ := chain.Next()
+
+	// A restart continues after the last handed out seed
+	loaded, err := LoadSeedChain(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.TerminalHash != chain.TerminalHash || loaded.Used != 1 {
+		t.Fatalf("loaded chain %s used %d, want %s used 1", loaded.TerminalHash, loaded.Used, chain.TerminalHash)
+	}
+	second, hash, position, _ := loaded.Next()
+	if position != 2 || hash != first || second == first {
+		t.Errorf("after reload got position %d, want 2 hashing to the first seed", position)
+	}
+
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSeedChain(path); err == nil || !strings.Contains(err.Error(), "other users") {
+		t.Errorf("world readable chain error = %v", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"origin":"aa","length":10,"terminalHash":"bb"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSeedChain(path); err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Errorf("tampered chain error = %v", err)
+	}
+	for _, length := range []int{0, MaxSeedChainLength + 1} {
+		if _, err := GenerateSeedChain(length, path); err == nil {
+			t.Errorf("chain of length %d generated", length)
+		}
+	}
+}
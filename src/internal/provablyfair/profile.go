@@ -0,0 +1,277 @@
+package provablyfair
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Profile kinds
+const (
+	KindWeighted = "weighted" // weighted buckets, uniform inside the bucket
+	KindInverse  = "inverse"  // (1-houseEdge)/(1-r) curve
+	KindCapped   = "capped"   // inverse curve capped at Cap
+)
+
+// Profile is a named and versioned crash point distribution.
+// A published version must never change, tune the table by adding a new version.
+type Profile struct {
+	Name      string        `json:"name"`
+	Version   int           `json:"version"`
+	Kind      string        `json:"kind"`
+	Ranges    []RangeWeight `json:"ranges,omitempty"`
+	HouseEdge float64       `json:"houseEdge,omitempty"`
+	Cap       float64       `json:"cap,omitempty"`
+}
+
+// ProfilesConfig is the layout of the profiles config file
+type ProfilesConfig struct {
+	Default  string    `json:"default"`
+	Profiles []Profile `json:"profiles"`
+}
+
+// LegacyProfile is the hard-coded table used before profiles existed.
+// Games without a profile name were played with it.
+var LegacyProfile = Profile{
+	Name:    "weighted",
+	Version: 1,
+	Kind:    KindWeighted,
+	Ranges: []RangeWeight{
+		{Min: 0.01, Max: 1, Weight: 0},
+		{Min: 1.01, Max: 2, Weight: 0.40},
+		{Min: 2.01, Max: 5, Weight: 0.20},
+		{Min: 5.01, Max: 10, Weight: 0.10},
+		{Min: 10.01, Max: 20, Weight: 0.10},
+		{Min: 20.01, Max: 35, Weight: 0.09},
+		{Min: 35.01, Max: 50, Weight: 0.07},
+		{Min: 50.01, Max: 80, Weight: 0.03},
+		{Min: 80.01, Max: 100, Weight: 0.01},
+	},
+}
+
+var (
+	profilesMu     sync.RWMutex
+	profiles       = map[string]map[int]Profile{LegacyProfile.Name: {LegacyProfile.Version: LegacyProfile}}
+	defaultProfile = LegacyProfile.Name
+)
+
+// LoadProfiles reads the profiles config file and registers every version in it.
+// The built-in legacy profile stays registered so old rounds keep verifying.
+func LoadProfiles(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cfg ProfilesConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return fmt.Errorf("invalid profiles config: %w", err)
+	}
+
+	loaded := map[string]map[int]Profile{LegacyProfile.Name: {LegacyProfile.Version: LegacyProfile}}
+	for _, p := range cfg.Profiles {
+		// The legacy version is built in and cannot be redefined
+		if p.Name == LegacyProfile.Name && p.Version == LegacyProfile.Version {
+			continue
+		}
+		if err := p.validate(); err != nil {
+			return err
+		}
+		if loaded[p.Name] == nil {
+			loaded[p.Name] = make(map[int]Profile)
+		}
+		if _, dup := loaded[p.Name][p.Version]; dup {
+			return fmt.Errorf("profile %s v%d defined twice", p.Name, p.Version)
+		}
+		loaded[p.Name][p.Version] = p
+	}
+	if cfg.Default == "" {
+		cfg.Default = LegacyProfile.Name
+	}
+	if _, ok := loaded[cfg.Default]; !ok {
+		return fmt.Errorf("default profile %s not defined", cfg.Default)
+	}
+
+	profilesMu.Lock()
+	profiles = loaded
+	defaultProfile = cfg.Default
+	profilesMu.Unlock()
+	return nil
+}
+
+// validate checks the parameters of a profile
+func (p Profile) validate() error {
+	if p.Name == "" || p.Version < 1 {
+		return fmt.Errorf("profile needs a name and a version >= 1")
+	}
+	switch p.Kind {
+	case KindWeighted:
+		if len(p.Ranges) == 0 {
+			return fmt.Errorf("profile %s v%d has no ranges", p.Name, p.Version)
+		}
+		total := 0.0
+		for _, r := range p.Ranges {
+			if r.Min > r.Max || r.Weight < 0 {
+				return fmt.Errorf("profile %s v%d has an invalid range", p.Name, p.Version)
+			}
+			total += r.Weight
+		}
+		if math.Abs(total-1) > 1e-9 {
+			return fmt.Errorf("profile %s v%d weights sum to %.4f", p.Name, p.Version, total)
+		}
+	case KindInverse, KindCapped:
+		if p.HouseEdge < 0 || p.HouseEdge >= 1 {
+			return fmt.Errorf("profile %s v%d has an invalid house edge", p.Name, p.Version)
+		}
+		if p.Kind == KindCapped && p.Cap <= 1 {
+			return fmt.Errorf("profile %s v%d needs a cap above 1", p.Name, p.Version)
+		}
+	default:
+		return fmt.Errorf("profile %s v%d has unknown kind %q", p.Name, p.Version, p.Kind)
+	}
+	return nil
+}
+
+// GetProfile returns a profile version, version 0 means the latest one.
+// An empty name resolves to the legacy profile so rounds stored before profiles verify.
+func GetProfile(name string, version int) (Profile, bool) {
+	if name == "" {
+		return LegacyProfile, true
+	}
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+
+	versions, ok := profiles[name]
+	if !ok {
+		return Profile{}, false
+	}
+	if version == 0 {
+		for v := range versions {
+			if v > version {
+				version = v
+			}
+		}
+	}
+	p, ok := versions[version]
+	return p, ok
+}
+
+// DefaultProfile returns the latest version of the default profile
+func DefaultProfile() Profile {
+	profilesMu.RLock()
+	name := defaultProfile
+	profilesMu.RUnlock()
+	p, _ := GetProfile(name, 0)
+	return p
+}
+
+// ListProfiles returns every registered profile version, sorted by name and version
+func ListProfiles() []Profile {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+
+	var list []Profile
+	for _, versions := range profiles {
+		for _, p := range versions {
+			list = append(list, p)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].Version < list[j].Version
+	})
+	return list
+}
+
+// apply turns the round hash into a crash point
+func (p Profile) apply(hash []byte, v *Verification) {
+	switch p.Kind {
+	case KindWeighted:
+		// First 8 bytes pick the bucket
+		num := binary.BigEndian.Uint64(hash[:8])
+		v.Ratio = float64(num) / float64(math.MaxUint64)
+		var selected RangeWeight
+		accum := 0.0
+		for i, r := range p.Ranges {
+			accum += r.Weight
+			if v.Ratio <= accum {
+				selected = r
+				v.Bucket = &selected
+				v.BucketIndex = i
+				break
+			}
+		}
+		// Weights may sum to a hair under 1, the top ratios belong to the last weighted bucket
+		if v.Bucket == nil {
+			for i := len(p.Ranges) - 1; i >= 0; i-- {
+				if p.Ranges[i].Weight > 0 {
+					selected = p.Ranges[i]
+					v.Bucket = &selected
+					v.BucketIndex = i
+					break
+				}
+			}
+		}
+
+		// Next 8 bytes pick the position inside the bucket
+		num2 := binary.BigEndian.Uint64(hash[8:16])
+		v.PositionRatio = float64(num2) / float64(math.MaxUint64)
+		multiplier := selected.Min + v.PositionRatio*(selected.Max-selected.Min)
+		v.Multiplier = math.Round(multiplier*100) / 100
+
+	case KindInverse, KindCapped:
+		// 52 bits give a uniform r in [0, 1) without float rounding up to 1
+		num := binary.BigEndian.Uint64(hash[:8]) >> 12
+		v.Ratio = float64(num) / float64(uint64(1)<<52)
+		multiplier := math.Floor((1-p.HouseEdge)/(1-v.Ratio)*100) / 100
+		if multiplier < 1 {
+			multiplier = 1
+		}
+		if p.Kind == KindCapped && multiplier > p.Cap {
+			multiplier = p.Cap
+		}
+		v.Multiplier = multiplier
+	}
+}
+
+// Survival returns the theoretical P(crash >= x) of the profile
+func (p Profile) Survival(x float64) float64 {
+	switch p.Kind {
+	case KindWeighted:
+		// Crash points are rounded to 2 decimals, so a bucket reaches x from x-0.005 on
+		total := 0.0
+		for _, r := range p.Ranges {
+			if r.Weight == 0 {
+				continue
+			}
+			if r.Max == r.Min {
+				if r.Min >= x {
+					total += r.Weight
+				}
+				continue
+			}
+			u := (x - 0.005 - r.Min) / (r.Max - r.Min)
+			total += r.Weight * (1 - math.Min(1, math.Max(0, u)))
+		}
+		return total
+	case KindInverse, KindCapped:
+		if p.Kind == KindCapped && x > p.Cap {
+			return 0
+		}
+		if x <= 1 {
+			return 1
+		}
+		return math.Min(1, (1-p.HouseEdge)/x)
+	}
+	return 0
+}
+
+// TheoreticalRTP returns the return to player of an auto-cashout at target
+func (p Profile) TheoreticalRTP(target float64) float64 {
+	return target * p.Survival(target)
+}
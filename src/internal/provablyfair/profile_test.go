@@ -0,0 +1,231 @@
+package provablyfair
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hashWith builds a round hash whose bucket and position ratios come from a and b
+func hashWith(a, b uint64) []byte {
+	hash := make([]byte, 32)
+	binary.BigEndian.PutUint64(hash[:8], a)
+	binary.BigEndian.PutUint64(hash[8:16], b)
+	return hash
+}
+
+func TestProfileValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		profile Profile
+		err     string
+	}{
+		{"legacy", LegacyProfile, ""},
+		{"inverse", Profile{Name: "i", Version: 1, Kind: KindInverse, HouseEdge: 0.01}, ""},
+		{"capped", Profile{Name: "c", Version: 1, Kind: KindCapped, HouseEdge: 0.01, Cap: 100}, ""},
+		{"no name", Profile{Version: 1, Kind: KindInverse}, "needs a name"},
+		{"version 0", Profile{Name: "i", Kind: KindInverse}, "needs a name"},
+		{"unknown kind", Profile{Name: "x", Version: 1, Kind: "linear"}, "unknown kind"},
+		{"no ranges", Profile{Name: "w", Version: 1, Kind: KindWeighted}, "no ranges"},
+		{"min above max", Profile{Name: "w", Version: 1, Kind: KindWeighted, Ranges: []RangeWeight{{Min: 2, Max: 1, Weight: 1}}}, "invalid range"},
+		{"negative weight", Profile{Name: "w", Version: 1, Kind: KindWeighted, Ranges: []RangeWeight{{Min: 1, Max: 2, Weight: -0.5}, {Min: 2, Max: 3, Weight: 1.5}}}, "invalid range"},
+		{"weights under 1", Profile{Name: "w", Version: 1, Kind: KindWeighted, Ranges: []RangeWeight{{Min: 1, Max: 2, Weight: 0.9}}}, "weights sum"},
+		{"weights over 1", Profile{Name: "w", Version: 1, Kind: KindWeighted, Ranges: []RangeWeight{{Min: 1, Max: 2, Weight: 0.6}, {Min: 2, Max: 3, Weight: 0.6}}}, "weights sum"},
+		{"house edge 1", Profile{Name: "i", Version: 1, Kind: KindInverse, HouseEdge: 1}, "house edge"},
+		{"negative house edge", Profile{Name: "i", Version: 1, Kind: KindInverse, HouseEdge: -0.01}, "house edge"},
+		{"cap 1", Profile{Name: "c", Version: 1, Kind: KindCapped, HouseEdge: 0.01, Cap: 1}, "cap above 1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.profile.validate()
+			switch {
+			case tc.err == "" && err != nil:
+				t.Errorf("validate: %v", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Errorf("validate error = %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestWeightedRatioAboveAccumulatedWeight(t *testing.T) {
+	// Sums to 1 within the validation tolerance, a ratio of 1 is above every accumulated weight
+	p := Profile{Name: "w", Version: 2, Kind: KindWeighted, Ranges: []RangeWeight{
+		{Min: 1.01, Max: 2, Weight: 0.5},
+		{Min: 2.01, Max: 5, Weight: 0.5 - 1e-12},
+		{Min: 5.01, Max: 10, Weight: 0},
+	}}
+	if err := p.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	v := Verification{BucketIndex: -1}
+	p.apply(hashWith(math.MaxUint64, 0), &v)
+	if v.Ratio != 1 {
+		t.Fatalf("ratio = %v, want 1", v.Ratio)
+	}
+	if v.Bucket == nil || v.BucketIndex != 1 || v.Multiplier != 2.01 {
+		t.Errorf("ratio above the weights picked bucket %d %+v, crash %.2f, want the last weighted bucket at 2.01",
+			v.BucketIndex, v.Bucket, v.Multiplier)
+	}
+}
+
+func TestWeightedBucketEdges(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		a, b   uint64
+		bucket int
+		crash  float64
+	}{
+		{"lowest ratio skips the empty bucket", 1, 0, 1, 1.01},
+		{"top of the first bucket", 1, math.MaxUint64, 1, 2},
+		{"highest ratio", math.MaxUint64, math.MaxUint64, 8, 100},
+	} {
+		v := Verification{BucketIndex: -1}
+		LegacyProfile.apply(hashWith(tc.a, tc.b), &v)
+		if v.BucketIndex != tc.bucket || v.Multiplier != tc.crash {
+			t.Errorf("%s: bucket %d crash %.2f, want bucket %d crash %.2f", tc.name, v.BucketIndex, v.Multiplier, tc.bucket, tc.crash)
+		}
+	}
+}
+
+func TestInverseCrashPoints(t *testing.T) {
+	inverse := Profile{Name: "i", Version: 1, Kind: KindInverse, HouseEdge: 0.01}
+	capped := Profile{Name: "c", Version: 1, Kind: KindCapped, HouseEdge: 0.01, Cap: 100}
+	for _, tc := range []struct {
+		profile Profile
+		r       uint64 // top 52 bits of the bucket ratio
+		crash   float64
+	}{
+		{inverse, 0, 1},
+		{inverse, 1 << 51, 1.98},
+		{inverse, 1<<52 - 1, 4458563631096791},
+		{capped, 1 << 51, 1.98},
+		{capped, 1<<52 - 1, 100},
+	} {
+		v := Verification{BucketIndex: -1}
+		tc.profile.apply(hashWith(tc.r<<12, 0), &v)
+		if math.Abs(v.Multiplier-tc.crash) > 1e-6 {
+			t.Errorf("%s r=%d crash %.2f, want %.2f", tc.profile.Kind, tc.r, v.Multiplier, tc.crash)
+		}
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	t.Cleanup(func() {
+		profilesMu.Lock()
+		profiles = map[string]map[int]Profile{LegacyProfile.Name: {LegacyProfile.Version: LegacyProfile}}
+		defaultProfile = LegacyProfile.Name
+		profilesMu.Unlock()
+	})
+	write := func(t *testing.T, cfg string) string {
+		path := filepath.Join(t.TempDir(), "profiles.json")
+		if err := os.WriteFile(path, []byte(cfg), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	for _, tc := range []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{"duplicate", `{"profiles":[{"name":"i","version":1,"kind":"inverse"},{"name":"i","version":1,"kind":"inverse"}]}`, "defined twice"},
+		{"unknown default", `{"default":"nope","profiles":[]}`, "not defined"},
+		{"invalid profile", `{"profiles":[{"name":"i","version":1,"kind":"inverse","houseEdge":2}]}`, "house edge"},
+		{"bad json", `{"profiles":`, "invalid profiles config"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := LoadProfiles(write(t, tc.cfg))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("LoadProfiles error = %v, want %q", err, tc.err)
+			}
+		})
+	}
+
+	// The legacy version cannot be redefined, later versions and other profiles load
+	path := write(t, `{"default":"standard","profiles":[
+		{"name":"weighted","version":1,"kind":"inverse","houseEdge":0.5},
+		{"name":"weighted","version":2,"kind":"weighted","ranges":[{"min":1,"max":2,"weight":1}]},
+		{"name":"standard","version":1,"kind":"inverse","houseEdge":0.01},
+		{"name":"standard","version":3,"kind":"inverse","houseEdge":0.03}]}`)
+	if err := LoadProfiles(path); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := GetProfile("weighted", 1); p.Kind != KindWeighted || len(p.Ranges) != len(LegacyProfile.Ranges) {
+		t.Errorf("weighted v1 = %+v, want the built-in legacy table", p)
+	}
+	if p, _ := GetProfile("weighted", 0); p.Version != 2 {
+		t.Errorf("latest weighted = v%d, want v2", p.Version)
+	}
+	if p := DefaultProfile(); p.Name != "standard" || p.Version != 3 {
+		t.Errorf("default = %s v%d, want standard v3", p.Name, p.Version)
+	}
+	if _, ok := GetProfile("standard", 2); ok {
+		t.Error("standard v2 found, it was never defined")
+	}
+	if got := len(ListProfiles()); got != 4 {
+		t.Errorf("listed %d profiles, want 4", got)
+	}
+}
+
+func TestSurvival(t *testing.T) {
+	inverse := Profile{Name: "i", Version: 1, Kind: KindInverse, HouseEdge: 0.01}
+	capped := Profile{Name: "c", Version: 1, Kind: KindCapped, HouseEdge: 0.01, Cap: 100}
+	for _, tc := range []struct {
+		name    string
+		profile Profile
+		x, want float64
+	}{
+		{"inverse at 1", inverse, 1, 1},
+		{"inverse at 2", inverse, 2, 0.495},
+		{"inverse at 99", inverse, 99, 0.01},
+		{"capped at its cap", capped, 100, 0.0099},
+		{"capped above its cap", capped, 100.01, 0},
+		{"legacy at 1.01", LegacyProfile, 1.01, 1},
+		{"legacy at 2.01", LegacyProfile, 2.01, 0.6},
+		{"legacy above 100", LegacyProfile, 100.01, 0},
+		{"unknown kind", Profile{Kind: "linear"}, 2, 0},
+	} {
+		if got := tc.profile.Survival(tc.x); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s: Survival(%.2f) = %v, want %v", tc.name, tc.x, got, tc.want)
+		}
+	}
+
+	if got := inverse.TheoreticalRTP(2); math.Abs(got-0.99) > 1e-9 {
+		t.Errorf("inverse RTP at 2x = %v, want 0.99", got)
+	}
+	if got := LegacyProfile.TheoreticalRTP(2.01); math.Abs(got-1.206) > 1e-9 {
+		t.Errorf("legacy RTP at 2.01x = %v, want 1.206", got)
+	}
+}
+
+func TestSurvivalMatchesRounds(t *testing.T) {
+	const rounds = 20000
+	profiles := []Profile{
+		LegacyProfile,
+		{Name: "i", Version: 1, Kind: KindInverse, HouseEdge: 0.01},
+		{Name: "c", Version: 1, Kind: KindCapped, HouseEdge: 0.04, Cap: 10},
+	}
+	for _, p := range profiles {
+		crashes := make([]float64, rounds)
+		for i := range crashes {
+			crashes[i] = VerifyRound(p, testSeed(i), "client", int64(i+1)).Multiplier
+		}
+		for _, x := range []float64{1.5, 2, 5, 10, 30} {
+			reached := 0
+			for _, c := range crashes {
+				if c >= x {
+					reached++
+				}
+			}
+			got, want := float64(reached)/rounds, p.Survival(x)
+			if math.Abs(got-want) > 0.015 {
+				t.Errorf("%s: P(crash >= %.2f) over %d rounds = %.4f, theory %.4f", p.Kind, x, rounds, got, want)
+			}
+		}
+	}
+}
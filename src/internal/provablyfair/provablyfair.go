@@ -4,9 +4,9 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
-	"encoding/binary"
 	"encoding/hex"
-	"math"
+	"strconv"
+	"strings"
 )
 
 func GenerateServerSeed() (string, string) {
@@ -16,49 +16,97 @@ func GenerateServerSeed() (string, string) {
 		panic(err)
 	}
 	seed := hex.EncodeToString(bytes)
-	hash := sha256.Sum256([]byte(seed))
-	return seed, hex.EncodeToString(hash[:]) // (serverSeed, serverSeedHash)
+	return seed, HashServerSeed(seed) // (serverSeed, serverSeedHash)
+}
+
+// HashServerSeed returns the SHA-256 commitment that is published while a round is open.
+func HashServerSeed(serverSeed string) string {
+	hash := sha256.Sum256([]byte(serverSeed))
+	return hex.EncodeToString(hash[:])
+}
+
+// VerifyServerSeed reports whether a revealed server seed matches its published commitment.
+func VerifyServerSeed(serverSeed, serverSeedHash string) bool {
+	return serverSeed != "" && HashServerSeed(serverSeed) == serverSeedHash
 }
 
 type RangeWeight struct {
-	Min    float64
-	Max    float64
-	Weight float64
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Weight float64 `json:"weight"`
+}
+
+// GenerateClientSeed returns a random client seed for players that did not set one.
+func GenerateClientSeed() string {
+	bytes := make([]byte, 16)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(bytes)
 }
 
-var weightedRanges = []RangeWeight{
-	{Min: 0.01, Max: 1, Weight: 0},
-	{Min: 1.01, Max: 2, Weight: 0.40},
-	{Min: 2.01, Max: 5, Weight: 0.20},
-	{Min: 5.01, Max: 10, Weight: 0.10},
-	{Min: 10.01, Max: 20, Weight: 0.10},
-	{Min: 20.01, Max: 35, Weight: 0.09},
-	{Min: 35.01, Max: 50, Weight: 0.07},
-	{Min: 50.01, Max: 80, Weight: 0.03},
-	{Min: 80.01, Max: 100, Weight: 0.01},
+// CombineClientSeeds builds the public round client seed from the players' seeds, in bet order.
+func CombineClientSeeds(clientSeeds []string) string {
+	if len(clientSeeds) == 0 {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(strings.Join(clientSeeds, ":")))
+	return hex.EncodeToString(hash[:])
+}
+
+// roundHash mixes the server seed with the round client seed and nonce.
+// Rounds without client seed and nonce keep the legacy "CrashGame" keyed hash so they still verify.
+func roundHash(seedBytes []byte, clientSeed string, nonce int64) []byte {
+	if clientSeed == "" && nonce == 0 {
+		h := hmac.New(sha256.New, []byte("CrashGame"))
+		h.Write(seedBytes)
+		return h.Sum(nil)
+	}
+	h := hmac.New(sha256.New, seedBytes)
+	h.Write([]byte(clientSeed + ":" + strconv.FormatInt(nonce, 10)))
+	return h.Sum(nil)
+}
+
+// Verification holds every intermediate value of a crash point calculation
+type Verification struct {
+	ServerSeed     string       `json:"serverSeed"`
+	ServerSeedHash string       `json:"serverSeedHash"`
+	ClientSeed     string       `json:"clientSeed"`
+	Nonce          int64        `json:"nonce"`
+	Profile        string       `json:"profile"`
+	ProfileVersion int          `json:"profileVersion"`
+	HMAC           string       `json:"hmac"`
+	Ratio          float64      `json:"ratio"`
+	BucketIndex    int          `json:"bucketIndex"`
+	Bucket         *RangeWeight `json:"bucket,omitempty"`
+	PositionRatio  float64      `json:"positionRatio"`
+	Multiplier     float64      `json:"multiplier"`
 }
 
-func CalculateCrashMultiplier(serverSeed string) float64 {
+// VerifyRound recomputes a round from its revealed seeds and reports every step.
+func VerifyRound(profile Profile, serverSeed, clientSeed string, nonce int64) Verification {
+	v := Verification{
+		ServerSeed:     serverSeed,
+		ServerSeedHash: HashServerSeed(serverSeed),
+		ClientSeed:     clientSeed,
+		Nonce:          nonce,
+		Profile:        profile.Name,
+		ProfileVersion: profile.Version,
+		BucketIndex:    -1,
+		Multiplier:     1.0,
+	}
 	seedBytes, err := hex.DecodeString(serverSeed)
 	if err != nil || len(seedBytes) == 0 {
-		return 1.0
-	}
-	h := hmac.New(sha256.New, []byte("CrashGame"))
-	h.Write(seedBytes)
-	hash := h.Sum(nil)
-	num := binary.BigEndian.Uint64(hash[:8])
-	ratio := float64(num) / float64(math.MaxUint64)
-	accum := 0.0
-	var selected RangeWeight
-	for _, r := range weightedRanges {
-		accum += r.Weight
-		if ratio <= accum {
-			selected = r
-			break
-		}
+		return v
 	}
-	num2 := binary.BigEndian.Uint64(hash[8:16])
-	ratio2 := float64(num2) / float64(math.MaxUint64)
-	multiplier := selected.Min + ratio2*(selected.Max-selected.Min)
-	return math.Round(multiplier*100) / 100
+	hash := roundHash(seedBytes, clientSeed, nonce)
+	v.HMAC = hex.EncodeToString(hash)
+	profile.apply(hash, &v)
+	return v
+}
+
+// CalculateCrashMultiplier returns the crash point of a round under the default profile.
+func CalculateCrashMultiplier(serverSeed, clientSeed string, nonce int64) float64 {
+	return VerifyRound(DefaultProfile(), serverSeed, clientSeed, nonce).Multiplier
 }
@@ -0,0 +1,117 @@
+package provablyfair
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// baselineCrash is CalculateCrashMultiplier as it was before client seeds and profiles.
+// Legacy rounds must keep verifying to exactly these values.
+func baselineCrash(serverSeed string) float64 {
+	weightedRanges := []RangeWeight{
+		{Min: 0.01, Max: 1, Weight: 0},
+		{Min: 1.01, Max: 2, Weight: 0.40},
+		{Min: 2.01, Max: 5, Weight: 0.20},
+		{Min: 5.01, Max: 10, Weight: 0.10},
+		{Min: 10.01, Max: 20, Weight: 0.10},
+		{Min: 20.01, Max: 35, Weight: 0.09},
+		{Min: 35.01, Max: 50, Weight: 0.07},
+		{Min: 50.01, Max: 80, Weight: 0.03},
+		{Min: 80.01, Max: 100, Weight: 0.01},
+	}
+	seedBytes, err := hex.DecodeString(serverSeed)
+	if err != nil || len(seedBytes) == 0 {
+		return 1.0
+	}
+	h := hmac.New(sha256.New, []byte("CrashGame"))
+	h.Write(seedBytes)
+	hash := h.Sum(nil)
+	num := binary.BigEndian.Uint64(hash[:8])
+	ratio := float64(num) / float64(math.MaxUint64)
+	accum := 0.0
+	var selected RangeWeight
+	for _, r := range weightedRanges {
+		accum += r.Weight
+		if ratio <= accum {
+			selected = r
+			break
+		}
+	}
+	num2 := binary.BigEndian.Uint64(hash[8:16])
+	ratio2 := float64(num2) / float64(math.MaxUint64)
+	multiplier := selected.Min + ratio2*(selected.Max-selected.Min)
+	return math.Round(multiplier*100) / 100
+}
+
+// testSeed is a fixed server seed, i-th of the tests
+func testSeed(i int) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("seed-%d", i)))
+	return hex.EncodeToString(h[:])
+}
+
+func TestLegacyRoundsMatchBaseline(t *testing.T) {
+	legacy, ok := GetProfile("", 0)
+	if !ok || legacy.Name != LegacyProfile.Name || legacy.Version != LegacyProfile.Version {
+		t.Fatalf("unnamed profile = %s v%d, want the legacy one", legacy.Name, legacy.Version)
+	}
+	for _, seed := range []string{"", "not hex", "00"} {
+		if got, want := VerifyRound(legacy, seed, "", 0).Multiplier, baselineCrash(seed); got != want {
+			t.Errorf("seed %q crash = %.2f, baseline %.2f", seed, got, want)
+		}
+	}
+	for i := 0; i < 5000; i++ {
+		seed := testSeed(i)
+		if got, want := VerifyRound(legacy, seed, "", 0).Multiplier, baselineCrash(seed); got != want {
+			t.Fatalf("seed %s crash = %.2f, baseline %.2f", seed, got, want)
+		}
+	}
+}
+
+func TestClientSeedAndNonceChangeTheRound(t *testing.T) {
+	seed := testSeed(1)
+	legacy := VerifyRound(LegacyProfile, seed, "", 0)
+	seen := map[string]bool{legacy.HMAC: true}
+	for _, round := range []struct {
+		clientSeed string
+		nonce      int64
+	}{
+		{"", 1},
+		{"a", 0},
+		{"a", 1},
+		{"b", 1},
+	} {
+		v := VerifyRound(LegacyProfile, seed, round.clientSeed, round.nonce)
+		if seen[v.HMAC] {
+			t.Errorf("client seed %q nonce %d reuses a round hash", round.clientSeed, round.nonce)
+		}
+		seen[v.HMAC] = true
+		if again := VerifyRound(LegacyProfile, seed, round.clientSeed, round.nonce); again.HMAC != v.HMAC || again.Multiplier != v.Multiplier {
+			t.Errorf("client seed %q nonce %d is not deterministic", round.clientSeed, round.nonce)
+		}
+	}
+}
+
+func TestCombineClientSeeds(t *testing.T) {
+	if got := CombineClientSeeds(nil); got != "" {
+		t.Errorf("no seeds combine to %q, want none", got)
+	}
+	ab := CombineClientSeeds([]string{"a", "b"})
+	if ab == "" || ab == CombineClientSeeds([]string{"b", "a"}) {
+		t.Errorf("combined seeds %q must depend on bet order", ab)
+	}
+}
+
+func TestServerSeedCommitment(t *testing.T) {
+	seed, hash := GenerateServerSeed()
+	if !VerifyServerSeed(seed, hash) {
+		t.Error("generated seed does not match its hash")
+	}
+	if VerifyServerSeed(testSeed(1), hash) || VerifyServerSeed("", HashServerSeed("")) {
+		t.Error("a different or empty seed matches the hash")
+	}
+}
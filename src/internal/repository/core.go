@@ -0,0 +1,488 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/grpcclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/he"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
+)
+
+// CoreGames stores games in a Core games table, finishing a game also writes its bets table
+type CoreGames struct {
+	table     string
+	betsTable string
+}
+
+// NewCoreGames returns the games repository of tables like g2_games and g2_bets
+func NewCoreGames(table, betsTable string) *CoreGames {
+	return &CoreGames{table: table, betsTable: betsTable}
+}
+
+func (g *CoreGames) Insert(game models.Game) (int64, error) {
+	gameJSON, err := json.Marshal(game)
+	if err != nil {
+		return 0, err
+	}
+	res, err := grpcclient.Insert(g.table).
+		Value("server_seed", game.ServerSeed).
+		Value("server_seed_hash", game.ServerSeedHash).
+		Value("game", string(gameJSON)).
+		Exec()
+	if err != nil {
+		return 0, err
+	}
+	if res.InsertedID < 1 {
+		return 0, fmt.Errorf("insert into %s: no ID", g.table)
+	}
+	return res.InsertedID, nil
+}
+
+func (g *CoreGames) Update(game models.Game, live bool) error {
+	gameJSON, err := json.Marshal(game)
+	if err != nil {
+		return err
+	}
+	isLive := 0
+	if live {
+		isLive = 1
+	}
+	res, err := grpcclient.Update(g.table).
+		Set("game", string(gameJSON)).
+		Set("is_live", isLive).
+		Where("id = ?", game.ID).
+		Exec()
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected == 0 {
+		return requireRow(g.table, game.ID)
+	}
+	return nil
+}
+
+// Finish checks the game row first, the committed transaction is the game finished
+func (g *CoreGames) Finish(game models.Game, bets []models.Bet, t *he.Tracker) error {
+	gameJSON, err := json.Marshal(game)
+	if err != nil {
+		return err
+	}
+	if err := requireRow(g.table, game.ID); err != nil {
+		return err
+	}
+	tx := grpcclient.Begin().Add(grpcclient.Update(g.table).
+		Set("game", string(gameJSON)).
+		Set("is_live", 0).
+		Where("id = ?", game.ID))
+	for _, bet := range bets {
+		betJSON, err := json.Marshal(bet)
+		if err != nil {
+			return err
+		}
+		tx.Add(grpcclient.Update(g.betsTable).
+			Set("bet", string(betJSON)).
+			Where("id = ?", bet.ID))
+	}
+	tx.Add(t.Update(g.table, int(game.ID)))
+
+	_, err = tx.Commit()
+	return err
+}
+
+func (g *CoreGames) Get(id int64) (models.Game, bool, error) {
+	rows, err := grpcclient.Select("id", "game", "is_live").
+		From(g.table).
+		Where("id = ?", id).
+		Rows()
+	if err != nil {
+		return models.Game{}, false, err
+	}
+	if len(rows) == 0 {
+		return models.Game{}, false, ErrNotFound
+	}
+	game, err := gameRow(rows[0])
+	return game, rows[0].Int("is_live") == 1, err
+}
+
+func (g *CoreGames) ListUnfinished() ([]models.Game, error) {
+	return g.list(grpcclient.Select("id", "game").
+		From(g.table).
+		Where("is_live = 1").
+		OrderBy("id"))
+}
+
+func (g *CoreGames) ListFinished(limit int) ([]models.Game, error) {
+	return g.list(grpcclient.Select("id", "game").
+		From(g.table).
+		Where("is_live = 0").
+		OrderBy("id DESC").
+		Limit(limit))
+}
+
+func (g *CoreGames) LastID() (int64, error) {
+	rows, err := grpcclient.Select("MAX(id) AS id").From(g.table).Rows()
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+	return rows[0].Int("id"), nil
+}
+
+// AvgHE is not known on a table without finished games, AVG() then gives a single NULL row
+func (g *CoreGames) AvgHE(limit int) (float64, bool) {
+	recent := grpcclient.Select("he").
+		From(g.table).
+		Where("is_live = 0 AND income > 0").
+		OrderBy("created_at DESC").
+		Limit(limit)
+	rows, err := grpcclient.Select("AVG(he) AS avg_he").FromSub(recent, "recent").Rows()
+	if err != nil || len(rows) == 0 || rows[0].Null("avg_he") {
+		return 0, false
+	}
+	return utils.RoundToTwoDigits(rows[0].Number("avg_he")), true
+}
+
+func (g *CoreGames) list(q *grpcclient.SelectQuery) ([]models.Game, error) {
+	rows, err := q.Rows()
+	if err != nil {
+		return nil, err
+	}
+	games := make([]models.Game, 0, len(rows))
+	for _, row := range rows {
+		game, err := gameRow(row)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	return games, nil
+}
+
+// gameRow decodes a game, the insert stores it before its ID is known
+func gameRow(row grpcclient.Row) (models.Game, error) {
+	var game models.Game
+	if err := json.Unmarshal([]byte(row.String("game")), &game); err != nil {
+		return game, fmt.Errorf("game %d: %w", row.Int("id"), err)
+	}
+	game.ID = row.Int("id")
+	return game, nil
+}
+
+// CoreBets stores bets in a Core bets table, its games table scopes the listings
+type CoreBets struct {
+	table      string
+	gamesTable string
+}
+
+// NewCoreBets returns the bets repository of a table like g2_bets
+func NewCoreBets(table, gamesTable string) *CoreBets {
+	return &CoreBets{table: table, gamesTable: gamesTable}
+}
+
+func (b *CoreBets) Insert(bet models.Bet) (int64, error) {
+	betJSON, err := json.Marshal(bet)
+	if err != nil {
+		return 0, err
+	}
+	res, err := grpcclient.Insert(b.table).
+		Value("user_id", bet.UserID).
+		Value("game_id", bet.GameID).
+		Value("bet", string(betJSON)).
+		Exec()
+	if err != nil {
+		return 0, err
+	}
+	if res.InsertedID < 1 {
+		return 0, fmt.Errorf("insert into %s: no ID", b.table)
+	}
+	return res.InsertedID, nil
+}
+
+func (b *CoreBets) Update(bet models.Bet) error {
+	betJSON, err := json.Marshal(bet)
+	if err != nil {
+		return err
+	}
+	res, err := grpcclient.Update(b.table).
+		Set("bet", string(betJSON)).
+		Where("id = ?", bet.ID).
+		Exec()
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected == 0 {
+		return requireRow(b.table, bet.ID)
+	}
+	return nil
+}
+
+func (b *CoreBets) Delete(id int64) error {
+	res, err := grpcclient.Delete(b.table).Where("id = ?", id).Exec()
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (b *CoreBets) Get(id int64) (models.Bet, error) {
+	bets, err := b.list(grpcclient.Select("id", "bet").
+		From(b.table).
+		Where("id = ?", id))
+	if err != nil {
+		return models.Bet{}, err
+	}
+	if len(bets) == 0 {
+		return models.Bet{}, ErrNotFound
+	}
+	return bets[0], nil
+}
+
+func (b *CoreBets) ListByUser(userID int64, limit int) ([]models.Bet, error) {
+	return b.list(grpcclient.Select("id", "bet").
+		From(b.table).
+		Where("user_id = ?", userID).
+		OrderBy("id DESC").
+		Limit(limit))
+}
+
+func (b *CoreBets) ListByGame(gameID int64) ([]models.Bet, error) {
+	return b.list(grpcclient.Select("id", "bet").
+		From(b.table).
+		Where("game_id = ?", gameID).
+		OrderBy("id"))
+}
+
+func (b *CoreBets) ListUnfinished() ([]models.Bet, error) {
+	return b.list(grpcclient.Select("b.id AS id", "b.bet AS bet").
+		FromAs(b.table, "b").
+		Join(b.gamesTable, "g", "g.id = b.game_id").
+		Where("g.is_live = 1").
+		OrderBy("b.id"))
+}
+
+func (b *CoreBets) ListFinished(from, to time.Time) ([]models.Bet, error) {
+	return b.list(grpcclient.Select("b.id AS id", "b.bet AS bet").
+		FromAs(b.table, "b").
+		Join(b.gamesTable, "g", "g.id = b.game_id").
+		Where("g.is_live = 0 AND g.created_at >= ? AND g.created_at < ?", from, to).
+		OrderBy("b.id"))
+}
+
+func (b *CoreBets) list(q *grpcclient.SelectQuery) ([]models.Bet, error) {
+	rows, err := q.Rows()
+	if err != nil {
+		return nil, err
+	}
+	bets := make([]models.Bet, 0, len(rows))
+	for _, row := range rows {
+		var bet models.Bet
+		if err := json.Unmarshal([]byte(row.String("bet")), &bet); err != nil {
+			return nil, fmt.Errorf("bet %d: %w", row.Int("id"), err)
+		}
+		bet.ID = row.Int("id")
+		bets = append(bets, bet)
+	}
+	return bets, nil
+}
+
+// requireRow returns ErrNotFound when the table has no row with the ID. MySQL counts the
+// rows an UPDATE changed, so an update with the stored values affects none.
+func requireRow(table string, id int64) error {
+	rows, err := grpcclient.Select("id").From(table).Where("id = ?", id).Rows()
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+var outboxColumns = []string{
+	"id", "idem_key", "room", "kind", "user_id", "game_id", "bet_id", "tx_type", "reference_id", "tx_ref",
+	"amount", "status", "attempts", "last_error", "next_at", "created_at", "updated_at",
+}
+
+// CoreOutbox stores the outbox in a Core table like g2_outbox, NOW() is Core's clock
+type CoreOutbox struct {
+	table string
+}
+
+// NewCoreOutbox returns the outbox repository of a table
+func NewCoreOutbox(table string) *CoreOutbox {
+	return &CoreOutbox{table: table}
+}
+
+func (o *CoreOutbox) Enqueue(e OutboxEntry) (OutboxEntry, error) {
+	_, err := grpcclient.Insert(o.table).
+		Value("idem_key", e.Key).
+		Value("room", e.Room).
+		Value("kind", e.Kind).
+		Value("user_id", e.UserID).
+		Value("game_id", e.GameID).
+		Value("bet_id", e.BetID).
+		Value("tx_type", e.TxType).
+		Value("reference_id", e.ReferenceID).
+		Value("tx_ref", e.TxRef).
+		Value("amount", utils.RoundToTwoDigits(e.Amount)).
+		Value("status", OutboxPending).
+		Expr("next_at", "NOW()").
+		OnDuplicateKeyUpdate("idem_key = idem_key").
+		Exec()
+	if err != nil {
+		return OutboxEntry{}, err
+	}
+
+	stored, found, err := o.Find(e.Key)
+	if err != nil {
+		return stored, err
+	}
+	if !found {
+		return stored, fmt.Errorf("entry %s not stored", e.Key)
+	}
+	return stored, nil
+}
+
+func (o *CoreOutbox) Find(key string) (OutboxEntry, bool, error) {
+	return o.first(o.selectAll().Where("idem_key = ?", key))
+}
+
+func (o *CoreOutbox) Get(id int64) (OutboxEntry, bool, error) {
+	return o.first(o.selectAll().Where("id = ?", id))
+}
+
+func (o *CoreOutbox) ListDue(limit int) ([]OutboxEntry, error) {
+	return o.list(o.selectAll().
+		Where("status = ? AND kind <> ? AND next_at <= NOW()", OutboxPending, OutboxDebit).
+		OrderBy("id").
+		Limit(limit))
+}
+
+func (o *CoreOutbox) ListStuck(limit int) ([]OutboxEntry, error) {
+	return o.list(o.selectAll().
+		Where("status IN (?, ?) OR (status = ? AND attempts > 0)", OutboxFailed, OutboxUnknown, OutboxPending).
+		OrderBy("id").
+		Limit(limit))
+}
+
+func (o *CoreOutbox) ListByStatus(status string, limit int) ([]OutboxEntry, error) {
+	return o.list(o.selectAll().
+		Where("status = ?", status).
+		OrderBy("id").
+		Limit(limit))
+}
+
+func (o *CoreOutbox) ListByBets(room string, fromBet, toBet int64) ([]OutboxEntry, error) {
+	return o.list(o.selectAll().
+		Where("room = ? AND bet_id BETWEEN ? AND ?", room, fromBet, toBet).
+		OrderBy("id"))
+}
+
+// Move reads a changed status as moved, every caller changes it
+func (o *CoreOutbox) Move(id int64, from string, u OutboxUpdate) (bool, error) {
+	lastError := u.LastError
+	if len(lastError) > 255 {
+		lastError = lastError[:255]
+	}
+	res, err := grpcclient.Update(o.table).
+		Set("status", u.Status).
+		Set("attempts", u.Attempts).
+		Set("last_error", lastError).
+		SetExpr("next_at", "DATE_ADD(NOW(), INTERVAL ? SECOND)", int(u.Delay.Seconds())).
+		Where("id = ? AND status = ?", id, from).
+		Exec()
+	if err != nil {
+		return false, err
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (o *CoreOutbox) MoveAll(kind, from, status, lastError string) (int, error) {
+	q := grpcclient.Update(o.table).
+		Set("status", status).
+		Set("last_error", lastError)
+	if kind == "" {
+		q.Where("status = ?", from)
+	} else {
+		q.Where("kind = ? AND status = ?", kind, from)
+	}
+	res, err := q.Exec()
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected), nil
+}
+
+func (o *CoreOutbox) selectAll() *grpcclient.SelectQuery {
+	return grpcclient.Select(outboxColumns...).From(o.table)
+}
+
+func (o *CoreOutbox) first(q *grpcclient.SelectQuery) (OutboxEntry, bool, error) {
+	entries, err := o.list(q)
+	if err != nil || len(entries) == 0 {
+		return OutboxEntry{}, false, err
+	}
+	return entries[0], true, nil
+}
+
+func (o *CoreOutbox) list(q *grpcclient.SelectQuery) ([]OutboxEntry, error) {
+	rows, err := q.Rows()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]OutboxEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, OutboxEntry{
+			ID:          row.Int("id"),
+			Key:         row.String("idem_key"),
+			Room:        row.String("room"),
+			Kind:        row.String("kind"),
+			UserID:      row.Int("user_id"),
+			GameID:      row.Int("game_id"),
+			BetID:       row.Int("bet_id"),
+			TxType:      row.String("tx_type"),
+			ReferenceID: row.String("reference_id"),
+			TxRef:       row.String("tx_ref"),
+			Amount:      row.Number("amount"),
+			Status:      row.String("status"),
+			Attempts:    int(row.Int("attempts")),
+			LastError:   row.String("last_error"),
+			NextAt:      row.String("next_at"),
+			CreatedAt:   row.String("created_at"),
+			UpdatedAt:   row.String("updated_at"),
+		})
+	}
+	return entries, nil
+}
+
+// CoreSagas stores the saga audit trail in a Core table like g2_bet_saga
+type CoreSagas struct {
+	table string
+}
+
+// NewCoreSagas returns the saga repository of a table
+func NewCoreSagas(table string) *CoreSagas {
+	return &CoreSagas{table: table}
+}
+
+func (s *CoreSagas) Record(step SagaStep) error {
+	detail := step.Detail
+	if len(detail) > 255 {
+		detail = detail[:255]
+	}
+	_, err := grpcclient.Insert(s.table).
+		Value("room", step.Room).
+		Value("game_id", step.GameID).
+		Value("bet_id", step.BetID).
+		Value("user_id", step.UserID).
+		Value("step", step.Step).
+		Value("status", step.Status).
+		Value("detail", detail).
+		Exec()
+	return err
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/fakecore"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/grpcclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/he"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+)
+
+// startCore points grpcclient at a fresh fake Core and returns the repositories of g2
+func startCore(t *testing.T) (*CoreGames, *CoreBets) {
+	t.Helper()
+	core, err := fakecore.Start("127.0.0.1:0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(core.Stop)
+	t.Setenv("CORE_GRPC_TOKEN", "")
+	grpcclient.Connect(core.Addr())
+	return NewCoreGames("g2_games", "g2_bets"), NewCoreBets("g2_bets", "g2_games")
+}
+
+func TestCoreUnchangedWritesSucceed(t *testing.T) {
+	games, bets := startCore(t)
+
+	game := models.Game{CrashAt: 2}
+	id, err := games.Insert(game)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.ID = id
+	bet := models.Bet{UserID: 7, GameID: id, Bet: 10}
+	if bet.ID, err = bets.Insert(bet); err != nil {
+		t.Fatal(err)
+	}
+
+	tracker := he.NewTracker()
+	tracker.AddIncome(10)
+	for i := 0; i < 2; i++ {
+		if err := games.Update(game, true); err != nil {
+			t.Errorf("update #%d: %v", i+1, err)
+		}
+		if err := bets.Update(bet); err != nil {
+			t.Errorf("bet update #%d: %v", i+1, err)
+		}
+	}
+	// A retried finish changes no row
+	for i := 0; i < 2; i++ {
+		if err := games.Finish(game, []models.Bet{bet}, tracker); err != nil {
+			t.Errorf("finish #%d: %v", i+1, err)
+		}
+	}
+	if _, live, err := games.Get(id); err != nil || live {
+		t.Errorf("finished game live=%v, %v", live, err)
+	}
+}
+
+func TestCoreMissingRowsAreNotFound(t *testing.T) {
+	games, bets := startCore(t)
+
+	if err := games.Update(models.Game{ID: 9}, true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update = %v, want %v", err, ErrNotFound)
+	}
+	if err := games.Finish(models.Game{ID: 9}, nil, he.NewTracker()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("finish = %v, want %v", err, ErrNotFound)
+	}
+	if err := bets.Update(models.Bet{ID: 9}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("bet update = %v, want %v", err, ErrNotFound)
+	}
+}
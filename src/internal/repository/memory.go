@@ -0,0 +1,450 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/he"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/utils"
+)
+
+// GameStats are the tracker totals stored with a finished game
+type GameStats struct {
+	Income  float64
+	Expense float64
+	ROI     float64
+	HE      float64
+}
+
+type memoryGame struct {
+	game      models.Game
+	live      bool
+	createdAt time.Time
+	stats     *GameStats
+}
+
+// MemoryGames stores games in memory, for tests and local runs without Core
+type MemoryGames struct {
+	mu     sync.RWMutex
+	games  map[int64]*memoryGame
+	lastID int64
+	now    func() time.Time
+	bets   *MemoryBets
+}
+
+// MemoryBets stores bets in memory, its games scope the listings
+type MemoryBets struct {
+	mu     sync.RWMutex
+	bets   map[int64]models.Bet
+	lastID int64
+	games  *MemoryGames
+}
+
+// NewMemory returns the empty games and bets repositories of one room
+func NewMemory() (*MemoryGames, *MemoryBets) {
+	games := &MemoryGames{games: map[int64]*memoryGame{}, now: time.Now}
+	games.bets = &MemoryBets{bets: map[int64]models.Bet{}, games: games}
+	return games, games.bets
+}
+
+func (g *MemoryGames) Insert(game models.Game) (int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.lastID++
+	game.ID = g.lastID
+	g.games[game.ID] = &memoryGame{game: game, live: true, createdAt: g.now().UTC()}
+	return game.ID, nil
+}
+
+func (g *MemoryGames) Update(game models.Game, live bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	stored, ok := g.games[game.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.game = game
+	stored.live = live
+	return nil
+}
+
+// Finish locks the bets before the games, like the bets listings
+func (g *MemoryGames) Finish(game models.Game, bets []models.Bet, t *he.Tracker) error {
+	income, expense, roi, houseEdge := t.Totals()
+	g.bets.mu.Lock()
+	defer g.bets.mu.Unlock()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stored, ok := g.games[game.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.game = game
+	stored.live = false
+	stored.stats = &GameStats{Income: income, Expense: expense, ROI: roi, HE: houseEdge}
+	// Like the Core update, a bet without a row is not stored
+	for _, bet := range bets {
+		if _, ok := g.bets.bets[bet.ID]; ok {
+			g.bets.bets[bet.ID] = bet
+		}
+	}
+	return nil
+}
+
+func (g *MemoryGames) Get(id int64) (models.Game, bool, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	stored, ok := g.games[id]
+	if !ok {
+		return models.Game{}, false, ErrNotFound
+	}
+	return stored.game, stored.live, nil
+}
+
+func (g *MemoryGames) ListUnfinished() ([]models.Game, error) {
+	var games []models.Game
+	for _, stored := range g.sorted(false) {
+		if stored.live {
+			games = append(games, stored.game)
+		}
+	}
+	return games, nil
+}
+
+func (g *MemoryGames) ListFinished(limit int) ([]models.Game, error) {
+	var games []models.Game
+	for _, stored := range g.sorted(true) {
+		if len(games) == limit {
+			break
+		}
+		if !stored.live {
+			games = append(games, stored.game)
+		}
+	}
+	return games, nil
+}
+
+func (g *MemoryGames) LastID() (int64, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.lastID, nil
+}
+
+func (g *MemoryGames) AvgHE(limit int) (float64, bool) {
+	sum, n := 0., 0
+	for _, stored := range g.sorted(true) {
+		if n == limit {
+			break
+		}
+		if !stored.live && stored.stats != nil && stored.stats.Income > 0 {
+			sum += stored.stats.HE
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return utils.RoundToTwoDigits(sum / float64(n)), true
+}
+
+// Stats returns the tracker totals saved for a game
+func (g *MemoryGames) Stats(id int64) (GameStats, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	stored, ok := g.games[id]
+	if !ok || stored.stats == nil {
+		return GameStats{}, false
+	}
+	return *stored.stats, true
+}
+
+// SetClock replaces the clock that stamps inserted games
+func (g *MemoryGames) SetClock(now func() time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.now = now
+}
+
+// sorted copies the games ordered by ID
+func (g *MemoryGames) sorted(desc bool) []memoryGame {
+	g.mu.RLock()
+	list := make([]memoryGame, 0, len(g.games))
+	for _, stored := range g.games {
+		list = append(list, *stored)
+	}
+	g.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		if desc {
+			return list[i].game.ID > list[j].game.ID
+		}
+		return list[i].game.ID < list[j].game.ID
+	})
+	return list
+}
+
+func (b *MemoryBets) Insert(bet models.Bet) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	bet.ID = b.lastID
+	b.bets[bet.ID] = bet
+	return bet.ID, nil
+}
+
+func (b *MemoryBets) Update(bet models.Bet) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.bets[bet.ID]; !ok {
+		return ErrNotFound
+	}
+	b.bets[bet.ID] = bet
+	return nil
+}
+
+func (b *MemoryBets) Delete(id int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.bets[id]; !ok {
+		return ErrNotFound
+	}
+	delete(b.bets, id)
+	return nil
+}
+
+func (b *MemoryBets) Get(id int64) (models.Bet, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	bet, ok := b.bets[id]
+	if !ok {
+		return models.Bet{}, ErrNotFound
+	}
+	return bet, nil
+}
+
+func (b *MemoryBets) ListByUser(userID int64, limit int) ([]models.Bet, error) {
+	list := b.filter(func(bet models.Bet) bool { return bet.UserID == userID })
+	sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func (b *MemoryBets) ListByGame(gameID int64) ([]models.Bet, error) {
+	return b.filter(func(bet models.Bet) bool { return bet.GameID == gameID }), nil
+}
+
+func (b *MemoryBets) ListUnfinished() ([]models.Bet, error) {
+	return b.filter(func(bet models.Bet) bool {
+		_, live, err := b.games.Get(bet.GameID)
+		return err == nil && live
+	}), nil
+}
+
+func (b *MemoryBets) ListFinished(from, to time.Time) ([]models.Bet, error) {
+	return b.filter(func(bet models.Bet) bool {
+		b.games.mu.RLock()
+		defer b.games.mu.RUnlock()
+		stored, ok := b.games.games[bet.GameID]
+		return ok && !stored.live && !stored.createdAt.Before(from) && stored.createdAt.Before(to)
+	}), nil
+}
+
+// filter copies the matching bets ordered by ID
+func (b *MemoryBets) filter(match func(models.Bet) bool) []models.Bet {
+	b.mu.RLock()
+	list := make([]models.Bet, 0)
+	for _, bet := range b.bets {
+		if match(bet) {
+			list = append(list, bet)
+		}
+	}
+	b.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// timeLayout formats the outbox times like Core's DATETIME
+const timeLayout = "2006-01-02 15:04:05"
+
+type memoryEntry struct {
+	entry  OutboxEntry
+	nextAt time.Time
+}
+
+// MemoryOutbox stores outbox entries in memory, for tests and local runs without Core
+type MemoryOutbox struct {
+	mu      sync.RWMutex
+	entries map[int64]*memoryEntry
+	keys    map[string]int64
+	lastID  int64
+	now     func() time.Time
+}
+
+// NewMemoryOutbox returns an empty outbox on the wall clock
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{entries: map[int64]*memoryEntry{}, keys: map[string]int64{}, now: time.Now}
+}
+
+func (o *MemoryOutbox) Enqueue(e OutboxEntry) (OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if id, ok := o.keys[e.Key]; ok {
+		return o.entries[id].entry, nil
+	}
+	now := o.now().UTC()
+	o.lastID++
+	e.ID = o.lastID
+	e.Amount = utils.RoundToTwoDigits(e.Amount)
+	e.Status = OutboxPending
+	e.Attempts = 0
+	e.LastError = ""
+	e.NextAt = now.Format(timeLayout)
+	e.CreatedAt = e.NextAt
+	e.UpdatedAt = e.NextAt
+	o.entries[e.ID] = &memoryEntry{entry: e, nextAt: now}
+	o.keys[e.Key] = e.ID
+	return e, nil
+}
+
+func (o *MemoryOutbox) Find(key string) (OutboxEntry, bool, error) {
+	o.mu.RLock()
+	id, ok := o.keys[key]
+	o.mu.RUnlock()
+	if !ok {
+		return OutboxEntry{}, false, nil
+	}
+	return o.Get(id)
+}
+
+func (o *MemoryOutbox) Get(id int64) (OutboxEntry, bool, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	stored, ok := o.entries[id]
+	if !ok {
+		return OutboxEntry{}, false, nil
+	}
+	return stored.entry, true, nil
+}
+
+func (o *MemoryOutbox) ListDue(limit int) ([]OutboxEntry, error) {
+	o.mu.RLock()
+	now := o.now()
+	o.mu.RUnlock()
+	return o.filter(limit, func(stored *memoryEntry) bool {
+		e := stored.entry
+		return e.Status == OutboxPending && e.Kind != OutboxDebit && !stored.nextAt.After(now)
+	}), nil
+}
+
+func (o *MemoryOutbox) ListStuck(limit int) ([]OutboxEntry, error) {
+	return o.filter(limit, func(stored *memoryEntry) bool {
+		e := stored.entry
+		return e.Status == OutboxFailed || e.Status == OutboxUnknown || (e.Status == OutboxPending && e.Attempts > 0)
+	}), nil
+}
+
+func (o *MemoryOutbox) ListByStatus(status string, limit int) ([]OutboxEntry, error) {
+	return o.filter(limit, func(stored *memoryEntry) bool {
+		return stored.entry.Status == status
+	}), nil
+}
+
+func (o *MemoryOutbox) ListByBets(room string, fromBet, toBet int64) ([]OutboxEntry, error) {
+	return o.filter(0, func(stored *memoryEntry) bool {
+		e := stored.entry
+		return e.Room == room && e.BetID >= fromBet && e.BetID <= toBet
+	}), nil
+}
+
+func (o *MemoryOutbox) Move(id int64, from string, u OutboxUpdate) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	stored, ok := o.entries[id]
+	if !ok || stored.entry.Status != from {
+		return false, nil
+	}
+	now := o.now().UTC()
+	stored.nextAt = now.Add(u.Delay.Truncate(time.Second))
+	stored.entry.Status = u.Status
+	stored.entry.Attempts = u.Attempts
+	stored.entry.LastError = u.LastError
+	stored.entry.NextAt = stored.nextAt.Format(timeLayout)
+	stored.entry.UpdatedAt = now.Format(timeLayout)
+	return true, nil
+}
+
+func (o *MemoryOutbox) MoveAll(kind, from, status, lastError string) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	updatedAt := o.now().UTC().Format(timeLayout)
+	moved := 0
+	for _, stored := range o.entries {
+		if stored.entry.Status != from || (kind != "" && stored.entry.Kind != kind) {
+			continue
+		}
+		stored.entry.Status = status
+		stored.entry.LastError = lastError
+		stored.entry.UpdatedAt = updatedAt
+		moved++
+	}
+	return moved, nil
+}
+
+// SetClock replaces the clock of the due times
+func (o *MemoryOutbox) SetClock(now func() time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.now = now
+}
+
+// filter copies the matching entries ordered by ID, at most limit of them unless it is 0
+func (o *MemoryOutbox) filter(limit int, match func(*memoryEntry) bool) []OutboxEntry {
+	o.mu.RLock()
+	list := make([]OutboxEntry, 0)
+	for _, stored := range o.entries {
+		if match(stored) {
+			list = append(list, stored.entry)
+		}
+	}
+	o.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+// MemorySagas keeps the saga audit trail in memory
+type MemorySagas struct {
+	mu    sync.Mutex
+	steps []SagaStep
+}
+
+// NewMemorySagas returns an empty audit trail
+func NewMemorySagas() *MemorySagas {
+	return &MemorySagas{}
+}
+
+func (s *MemorySagas) Record(step SagaStep) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, step)
+	return nil
+}
+
+// Steps returns the recorded steps of a bet in order
+func (s *MemorySagas) Steps(betID int64) []SagaStep {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var steps []SagaStep
+	for _, step := range s.steps {
+		if step.BetID == betID {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/he"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/models"
+)
+
+// Games and bets of a room are stored as JSON documents keyed by their row ID.
+// The Core implementation keeps them in the room's <prefix>_games and <prefix>_bets
+// tables, the memory one keeps them in maps so rounds can run without Core.
+// The outbox and the saga audit trail are shared by every room.
+
+// ErrNotFound is returned when no row has the ID
+var ErrNotFound = errors.New("not found")
+
+// GameRepository stores the games of a room. A game is live until it is updated as
+// finished, its server seed is revealed from then on.
+type GameRepository interface {
+	// Insert stores a new live game and returns its ID
+	Insert(game models.Game) (int64, error)
+	// Update stores the game, live false finishes it
+	Update(game models.Game, live bool) error
+	// Finish stores the game as finished with its bets and tracker totals, all or nothing
+	Finish(game models.Game, bets []models.Bet, t *he.Tracker) error
+	// Get loads a game and whether it is still live
+	Get(id int64) (models.Game, bool, error)
+	// ListUnfinished loads the live games, oldest first
+	ListUnfinished() ([]models.Game, error)
+	// ListFinished loads the last finished games, newest first
+	ListFinished(limit int) ([]models.Game, error)
+	// LastID is the highest game ID, 0 when there is none
+	LastID() (int64, error)
+	// AvgHE is the average HE of the last finished games with income
+	AvgHE(limit int) (float64, bool)
+}
+
+// BetRepository stores the bets of a room
+type BetRepository interface {
+	// Insert stores a new bet and returns its ID
+	Insert(bet models.Bet) (int64, error)
+	// Update stores the bet
+	Update(bet models.Bet) error
+	// Delete removes a bet that was never placed
+	Delete(id int64) error
+	// Get loads a bet
+	Get(id int64) (models.Bet, error)
+	// ListByUser loads the last bets of a user, newest first
+	ListByUser(userID int64, limit int) ([]models.Bet, error)
+	// ListByGame loads the bets of a game, oldest first
+	ListByGame(gameID int64) ([]models.Bet, error)
+	// ListUnfinished loads the bets of the live games, oldest first
+	ListUnfinished() ([]models.Bet, error)
+	// ListFinished loads the bets of the finished games created in [from, to), oldest first
+	ListFinished(from, to time.Time) ([]models.Bet, error)
+}
+
+// Outbox entry kinds, each one moves money of one bet at most once
+const (
+	OutboxDebit  = "debit"  // stake, sent inline by AddBet only
+	OutboxWin    = "win"    // payout
+	OutboxRefund = "refund" // stake back
+
+	OutboxCorrection = "correction" // queued by the reconciliation, either direction
+)
+
+// Outbox entry statuses
+const (
+	OutboxPending = "pending"
+	OutboxSending = "sending" // claimed by one sender
+	OutboxDone    = "done"
+	OutboxFailed  = "failed"  // rejected debit or credit out of attempts, needs an admin
+	OutboxUnknown = "unknown" // sent without an answer, an admin checks UM and resolves it
+)
+
+// OutboxEntry is one UM transaction of a bet
+type OutboxEntry struct {
+	ID          int64   `json:"id"`
+	Key         string  `json:"key"`
+	Room        string  `json:"room"`
+	Kind        string  `json:"kind"`
+	UserID      int64   `json:"userID"`
+	GameID      int64   `json:"gameID"`
+	BetID       int64   `json:"betID"`
+	TxType      string  `json:"txType"`
+	ReferenceID string  `json:"referenceID"`
+	TxRef       string  `json:"txRef"`
+	Amount      float64 `json:"amount"`
+	Status      string  `json:"status"`
+	Attempts    int     `json:"attempts"`
+	LastError   string  `json:"lastError,omitempty"`
+	NextAt      string  `json:"nextAt"`
+	CreatedAt   string  `json:"createdAt"`
+	UpdatedAt   string  `json:"updatedAt"`
+}
+
+// OutboxUpdate is the next state of an entry, it is due Delay after now
+type OutboxUpdate struct {
+	Status    string
+	Attempts  int
+	LastError string
+	Delay     time.Duration
+}
+
+// OutboxRepository stores the UM transactions of the bets, each under its idempotency key
+type OutboxRepository interface {
+	// Enqueue stores a pending entry due now and returns it as stored. An entry already
+	// stored under the key is kept and returned instead.
+	Enqueue(e OutboxEntry) (OutboxEntry, error)
+	// Find loads an entry by its idempotency key
+	Find(key string) (OutboxEntry, bool, error)
+	// Get loads an entry by ID
+	Get(id int64) (OutboxEntry, bool, error)
+	// ListDue loads the pending credits that are due, oldest first
+	ListDue(limit int) ([]OutboxEntry, error)
+	// ListStuck loads the failed and unknown entries and the pending ones that already
+	// failed an attempt, oldest first
+	ListStuck(limit int) ([]OutboxEntry, error)
+	// ListByStatus loads the entries in a status, oldest first
+	ListByStatus(status string, limit int) ([]OutboxEntry, error)
+	// ListByBets loads the entries of the bets of a room with IDs in [fromBet, toBet]
+	ListByBets(room string, fromBet, toBet int64) ([]OutboxEntry, error)
+	// Move updates an entry in status from, false when it is not in it
+	Move(id int64, from string, u OutboxUpdate) (bool, error)
+	// MoveAll sets the status and last error of the entries in status from, of one kind
+	// or of any kind when kind is empty
+	MoveAll(kind, from, status, lastError string) (int, error)
+}
+
+// SagaStep is one step of the AddBet saga of a bet
+type SagaStep struct {
+	Room   string
+	GameID int64
+	BetID  int64
+	UserID int64
+	Step   string
+	Status string
+	Detail string
+}
+
+// SagaRepository stores the audit trail of the AddBet saga
+type SagaRepository interface {
+	// Record stores a step
+	Record(step SagaStep) error
+}
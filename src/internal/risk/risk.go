@@ -0,0 +1,125 @@
+package risk
+
+import (
+	"log"
+	"time"
+)
+
+// Policy is the set of published levers applied to a round.
+// The crash point itself always comes from the seeds; risk control only moves these limits.
+type Policy struct {
+	Tier          string  `json:"tier"`
+	Profile       string  `json:"profile"`
+	MaxWin        float64 `json:"maxWin"`        // largest payout a single bet can receive
+	MaxBet        float64 `json:"maxBet"`        // largest single bet
+	MaxUserBets   int     `json:"maxUserBets"`   // bets per user per round
+	MaxUserTotal  float64 `json:"maxUserTotal"`  // total stake per user per round
+	MaxRoundTotal float64 `json:"maxRoundTotal"` // total stake of all users per round
+}
+
+// Decision records which policy was chosen for a game and why
+type Decision struct {
+	GameID    int64     `json:"gameID"`
+	AvgHE     float64   `json:"avgHE"`
+	HEKnown   bool      `json:"heKnown"`
+	Policy    Policy    `json:"policy"`
+	Reason    string    `json:"reason"`
+	DecidedAt time.Time `json:"decidedAt"`
+}
+
+const (
+	TierStandard  = "standard"
+	TierReduced   = "reduced"
+	TierDefensive = "defensive"
+)
+
+// Thresholds on the average house edge (percent) of recent games
+const (
+	ReducedBelowHE   = 8.0
+	DefensiveBelowHE = 0.0
+)
+
+// Distribution profiles of the tiers, defined in configs/crash_profiles.json.
+// Below target the rounds move to the 1% house edge curve, capped when defensive.
+const (
+	ProfileStandard  = "weighted"
+	ProfileReduced   = "standard"
+	ProfileDefensive = "capped"
+)
+
+// Tiers is the published table of policies
+var Tiers = map[string]Policy{
+	TierStandard: {
+		Tier:          TierStandard,
+		Profile:       ProfileStandard,
+		MaxWin:        10000,
+		MaxBet:        200,
+		MaxUserBets:   10,
+		MaxUserTotal:  200,
+		MaxRoundTotal: 10000,
+	},
+	TierReduced: {
+		Tier:          TierReduced,
+		Profile:       ProfileReduced,
+		MaxWin:        2000,
+		MaxBet:        200,
+		MaxUserBets:   10,
+		MaxUserTotal:  200,
+		MaxRoundTotal: 5000,
+	},
+	TierDefensive: {
+		Tier:          TierDefensive,
+		Profile:       ProfileDefensive,
+		MaxWin:        500,
+		MaxBet:        100,
+		MaxUserBets:   5,
+		MaxUserTotal:  100,
+		MaxRoundTotal: 2500,
+	},
+}
+
+// Decide picks the policy of a game from the average house edge of recent games
+func Decide(gameID int64, avgHE float64, heKnown bool) Decision {
+	d := Decision{
+		GameID:    gameID,
+		AvgHE:     avgHE,
+		HEKnown:   heKnown,
+		DecidedAt: time.Now().UTC(),
+	}
+	switch {
+	case !heKnown:
+		d.Policy = Tiers[TierStandard]
+		d.Reason = "no house edge history"
+	case avgHE < DefensiveBelowHE:
+		d.Policy = Tiers[TierDefensive]
+		d.Reason = "average house edge negative"
+	case avgHE < ReducedBelowHE:
+		d.Policy = Tiers[TierReduced]
+		d.Reason = "average house edge below target"
+	default:
+		d.Policy = Tiers[TierStandard]
+		d.Reason = "average house edge on target"
+	}
+	log.Printf("Game %d risk %s (avgHE %.2f, known %t): %s", gameID, d.Policy.Tier, avgHE, heKnown, d.Reason)
+	return d
+}
+
+// CapWin applies the max win lever to a payout
+func (p Policy) CapWin(payout float64) float64 {
+	if p.MaxWin > 0 && payout > p.MaxWin {
+		return p.MaxWin
+	}
+	return payout
+}
+
+// Scale multiplies the amount limits of a policy, the bet count stays the same
+func (p Policy) Scale(f float64) Policy {
+	if f <= 0 || f == 1 {
+		return p
+	}
+	p.MaxWin *= f
+	p.MaxBet *= f
+	p.MaxUserTotal *= f
+	p.MaxRoundTotal *= f
+	return p
+}
@@ -0,0 +1,91 @@
+package risk
+
+import (
+	"testing"
+
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/provablyfair"
+)
+
+func TestDecideThresholds(t *testing.T) {
+	for _, tc := range []struct {
+		avgHE   float64
+		heKnown bool
+		tier    string
+	}{
+		{-50, false, TierStandard},
+		{-0.01, true, TierDefensive},
+		{DefensiveBelowHE, true, TierReduced},
+		{ReducedBelowHE - 0.01, true, TierReduced},
+		{ReducedBelowHE, true, TierStandard},
+		{40, true, TierStandard},
+	} {
+		d := Decide(1, tc.avgHE, tc.heKnown)
+		if d.Policy != Tiers[tc.tier] {
+			t.Errorf("Decide(%.2f, known %t) tier = %s, want %s", tc.avgHE, tc.heKnown, d.Policy.Tier, tc.tier)
+		}
+		if d.GameID != 1 || d.AvgHE != tc.avgHE || d.HEKnown != tc.heKnown || d.Reason == "" || d.DecidedAt.IsZero() {
+			t.Errorf("Decide(%.2f, known %t) recorded %+v", tc.avgHE, tc.heKnown, d)
+		}
+	}
+}
+
+func TestTierProfilesDiffer(t *testing.T) {
+	if err := provablyfair.LoadProfiles("../../configs/crash_profiles.json"); err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]string{}
+	for name, p := range Tiers {
+		if other, dup := seen[p.Profile]; dup {
+			t.Errorf("tiers %s and %s share profile %s", name, other, p.Profile)
+		}
+		seen[p.Profile] = name
+		if _, ok := provablyfair.GetProfile(p.Profile, 0); !ok {
+			t.Errorf("tier %s profile %s is not in the profiles config", name, p.Profile)
+		}
+	}
+
+	// Tighter tiers never pay more on average than the standard one
+	standard, _ := provablyfair.GetProfile(ProfileStandard, 0)
+	for _, name := range []string{ProfileReduced, ProfileDefensive} {
+		p, _ := provablyfair.GetProfile(name, 0)
+		for _, target := range []float64{1.5, 2, 10, 50} {
+			if p.TheoreticalRTP(target) > standard.TheoreticalRTP(target) {
+				t.Errorf("profile %s RTP at %.2fx above the standard tier", name, target)
+			}
+		}
+	}
+}
+
+func TestCapWin(t *testing.T) {
+	p := Policy{MaxWin: 500}
+	for _, tc := range []struct{ payout, want float64 }{
+		{0, 0},
+		{499.99, 499.99},
+		{500, 500},
+		{500.01, 500},
+		{10000, 500},
+	} {
+		if got := p.CapWin(tc.payout); got != tc.want {
+			t.Errorf("CapWin(%.2f) = %.2f, want %.2f", tc.payout, got, tc.want)
+		}
+	}
+	if got := (Policy{}).CapWin(10000); got != 10000 {
+		t.Errorf("CapWin without a max win = %.2f, want the payout", got)
+	}
+}
+
+func TestScale(t *testing.T) {
+	p := Tiers[TierReduced]
+	for _, f := range []float64{-1, 0, 1} {
+		if got := p.Scale(f); got != p {
+			t.Errorf("Scale(%v) = %+v, want the policy unchanged", f, got)
+		}
+	}
+
+	got := p.Scale(2.5)
+	want := p
+	want.MaxWin, want.MaxBet, want.MaxUserTotal, want.MaxRoundTotal = 5000, 500, 500, 12500
+	if got != want {
+		t.Errorf("Scale(2.5) = %+v, want %+v", got, want)
+	}
+}
@@ -24,6 +24,14 @@ func dispatchWeb(w http.ResponseWriter, fn func(map[string]interface{}) (models.
 var postRoutes = map[string]func(map[string]interface{}) (models.HandlerOK, models.HandlerError){
 	// Ping
 	"ping": handlers.Ping,
+
+	// Rooms
+	"getRooms":       handlers.GetRooms,
+	"getRoomTimings": handlers.GetRoomTimings,
+
+	// Provably Fair
+	"verifyRound":      handlers.VerifyRound,
+	"getCrashProfiles": handlers.GetCrashProfiles,
 }
 
 func HandleHTTP(w http.ResponseWriter, r *http.Request) {
@@ -57,10 +57,67 @@ var wsRoutes = map[string]func(*ConnInfo, map[string]interface{}, int64){
 		dispatch(ci, reqId, handlers.GetLeaderboard, d)
 	},
 
+	// Provably Fair
+	"getCrashProfiles": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.GetCrashProfiles, d)
+	},
+	"verifyRound": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.VerifyRound, d)
+	},
+	"getSeedChain": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.GetSeedChain, d)
+	},
+	"setClientSeed": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.SetClientSeed, d)
+	},
+	"getReveals": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.GetReveals, d)
+	},
+
+	// Rooms
+	"getRooms": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.GetRooms, d)
+	},
+
 	// Crash History
 	"getLiveGame": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
 		dispatch(ci, reqId, handlers.GetLiveGame, d)
 	},
+	"getRiskPolicy": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.GetRiskPolicy, d)
+	},
+	"getRoomTimings": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.GetRoomTimings, d)
+	},
+
+	// Admin
+	"getSeedChainStatus": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.GetSeedChainStatus, d)
+	},
+	"rotateSeedChain": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.RotateSeedChain, d)
+	},
+	"setRoomTimings": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.SetRoomTimings, d)
+	},
+	"pauseRooms": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.PauseRooms, d)
+	},
+	"resumeRooms": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.ResumeRooms, d)
+	},
+	"setMaintenance": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.SetMaintenance, d)
+	},
+	"getOutbox": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.GetOutbox, d)
+	},
+	"retryOutbox": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.RetryOutbox, d)
+	},
+	"resolveOutbox": func(ci *ConnInfo, d map[string]interface{}, reqId int64) {
+		dispatch(ci, reqId, handlers.ResolveOutbox, d)
+	},
 }
 
 func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
@@ -102,13 +159,13 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	})
 
 	// Main loop
-	var msg models.Request
 	for {
 		_, data, err := conn.ReadMessage()
 		if err != nil {
 			log.Println("read error:", err)
 			break
 		}
+		var msg models.Request
 		if err := json.Unmarshal(data, &msg); err != nil {
 			SendError(ci, 0, "INVALID_JSON_BODY", 1002, "")
 			continue
@@ -122,6 +179,18 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 			log.Println("Web Req:", msg.Type)
 		}
 
+		// Room: a request naming a room joins it, others run in the joined room
+		if msg.Room != "" {
+			if _, found := handlers.GetRoom(msg.Room); found {
+				JoinRoom(conn, msg.Room)
+			}
+		} else {
+			msg.Room = ConnRoom(conn)
+		}
+		if _, exists := reqData["room"]; !exists && msg.Room != "" {
+			reqData["room"] = msg.Room
+		}
+
 		// Special case: bind
 		if msg.Type == "bind" {
 			SendResponse(ci, 1, "bind.ok", map[string]any{
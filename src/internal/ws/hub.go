@@ -3,6 +3,7 @@ package ws
 import (
 	"encoding/json"
 	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/events"
+	"github.com/Milad-Abooali/4in-cs2skin-g2/src/internal/handlers"
 	"github.com/gorilla/websocket"
 	"sync"
 	"time"
@@ -11,6 +12,7 @@ import (
 type ConnInfo struct {
 	Conn     *websocket.Conn
 	UserID   int64
+	Room     string // joined crash room, empty is the default room
 	SendChan chan []byte
 }
 
@@ -90,6 +92,27 @@ func BindUser(c *websocket.Conn, userID int64) {
 	}
 }
 
+// JoinRoom moves the connection to a crash room, it then only gets that room's events
+func JoinRoom(c *websocket.Conn, room string) {
+	regMu.Lock()
+	defer regMu.Unlock()
+
+	if ci, ok := byConn[c]; ok {
+		ci.Room = room
+	}
+}
+
+// ConnRoom returns the room the connection joined
+func ConnRoom(c *websocket.Conn) string {
+	regMu.RLock()
+	defer regMu.RUnlock()
+
+	if ci, ok := byConn[c]; ok {
+		return ci.Room
+	}
+	return ""
+}
+
 // === Writer Goroutine ===
 
 func (ci *ConnInfo) startWriter() {
@@ -152,6 +175,23 @@ func EmitToAny(payload any) {
 	emitToTargets(targets, payload)
 }
 
+func EmitToRoom(room string, payload any) {
+	defaultRoom := handlers.DefaultRoom().ID
+	regMu.RLock()
+	var targets []*ConnInfo
+	for _, ci := range byConn {
+		joined := ci.Room
+		if joined == "" {
+			joined = defaultRoom
+		}
+		if joined == room {
+			targets = append(targets, ci)
+		}
+	}
+	regMu.RUnlock()
+	emitToTargets(targets, payload)
+}
+
 func EmitToGuests(payload any) {
 	regMu.RLock()
 	var targets []*ConnInfo
@@ -198,12 +238,21 @@ func EmitToGuestsEvent(eventType string, data any) {
 	})
 }
 
+func EmitToRoomEvent(room string, eventType string, data any) {
+	EmitToRoom(room, map[string]any{
+		"type": eventType,
+		"room": room,
+		"data": data,
+		"at":   time.Now().UnixMilli(),
+	})
+}
+
 func EmitServer(req map[string]interface{}, resType string, resData interface{}) {
 	switch resType {
 	case "test":
 		// No Emit
 	default:
-		// Example: EmitToAnyEvent("heartbeat", handlers.LiveGame)
+		// Example: EmitToAnyEvent("heartbeat", handlers.DefaultRoom().Engine.Live())
 	}
 }
 
@@ -221,6 +270,8 @@ func EmitEventLoop() {
 				EmitToAllUsersEvent(ev.Type, ev.Data)
 			case "guests":
 				EmitToGuestsEvent(ev.Type, ev.Data)
+			case "room":
+				EmitToRoomEvent(ev.Room, ev.Type, ev.Data)
 			}
 		}
 	}()
@@ -0,0 +1,21 @@
+-- Every UM transaction of the game, recorded before it is sent
+CREATE TABLE IF NOT EXISTS g2_outbox (
+  id           BIGINT AUTO_INCREMENT PRIMARY KEY,
+  idem_key     VARCHAR(128) NOT NULL UNIQUE,
+  room         VARCHAR(32) NOT NULL,
+  kind         VARCHAR(16) NOT NULL,
+  user_id      BIGINT NOT NULL,
+  game_id      BIGINT NOT NULL,
+  bet_id       BIGINT NOT NULL,
+  tx_type      VARCHAR(32) NOT NULL,
+  reference_id VARCHAR(64) NOT NULL,
+  tx_ref       VARCHAR(64) NOT NULL,
+  amount       DECIMAL(16,2) NOT NULL,
+  status       VARCHAR(16) NOT NULL,
+  attempts     INT NOT NULL DEFAULT 0,
+  last_error   VARCHAR(255) NOT NULL DEFAULT '',
+  next_at      DATETIME NOT NULL,
+  created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  KEY status_next (status, next_at)
+);
@@ -0,0 +1,13 @@
+-- Audit trail of the AddBet saga, one row per step
+CREATE TABLE IF NOT EXISTS g2_bet_saga (
+  id         BIGINT AUTO_INCREMENT PRIMARY KEY,
+  room       VARCHAR(32) NOT NULL,
+  game_id    BIGINT NOT NULL,
+  bet_id     BIGINT NOT NULL,
+  user_id    BIGINT NOT NULL,
+  step       VARCHAR(16) NOT NULL,
+  status     VARCHAR(16) NOT NULL,
+  detail     VARCHAR(255) NOT NULL DEFAULT '',
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  KEY bet (room, bet_id)
+);
@@ -0,0 +1,9 @@
+-- Tables of the extra rooms in configs/crash_rooms.example.json. Every room needs
+-- <tablePrefix>_games and <tablePrefix>_bets shaped like the main room's g2 tables,
+-- run this before adding the rooms to configs/crash_rooms.json.
+CREATE TABLE IF NOT EXISTS g2_low_games LIKE g2_games;
+CREATE TABLE IF NOT EXISTS g2_low_bets LIKE g2_bets;
+CREATE TABLE IF NOT EXISTS g2_high_games LIKE g2_games;
+CREATE TABLE IF NOT EXISTS g2_high_bets LIKE g2_bets;
+CREATE TABLE IF NOT EXISTS g2_turbo_games LIKE g2_games;
+CREATE TABLE IF NOT EXISTS g2_turbo_bets LIKE g2_bets;
@@ -19,7 +19,10 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	DataService_Query_FullMethodName = "/proto.DataService/Query"
+	DataService_Query_FullMethodName       = "/proto.DataService/Query"
+	DataService_Exec_FullMethodName        = "/proto.DataService/Exec"
+	DataService_QueryParams_FullMethodName = "/proto.DataService/QueryParams"
+	DataService_Transaction_FullMethodName = "/proto.DataService/Transaction"
 )
 
 // DataServiceClient is the client API for DataService service.
@@ -27,6 +30,15 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type DataServiceClient interface {
 	Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error)
+	// Exec runs one INSERT, UPDATE or DELETE with bound parameters,
+	// data holds inserted_id and rows_affected like Query.
+	Exec(ctx context.Context, in *ParamsRequest, opts ...grpc.CallOption) (*QueryResponse, error)
+	// QueryParams runs one SELECT with bound parameters,
+	// data holds count and rows like Query.
+	QueryParams(ctx context.Context, in *ParamsRequest, opts ...grpc.CallOption) (*QueryResponse, error)
+	// Transaction runs statements in order in one database transaction,
+	// they are all committed or all rolled back.
+	Transaction(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*TransactionResponse, error)
 }
 
 type dataServiceClient struct {
@@ -46,11 +58,47 @@ func (c *dataServiceClient) Query(ctx context.Context, in *QueryRequest, opts ..
 	return out, nil
 }
 
+func (c *dataServiceClient) Exec(ctx context.Context, in *ParamsRequest, opts ...grpc.CallOption) (*QueryResponse, error) {
+	out := new(QueryResponse)
+	err := c.cc.Invoke(ctx, DataService_Exec_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataServiceClient) QueryParams(ctx context.Context, in *ParamsRequest, opts ...grpc.CallOption) (*QueryResponse, error) {
+	out := new(QueryResponse)
+	err := c.cc.Invoke(ctx, DataService_QueryParams_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataServiceClient) Transaction(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*TransactionResponse, error) {
+	out := new(TransactionResponse)
+	err := c.cc.Invoke(ctx, DataService_Transaction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DataServiceServer is the server API for DataService service.
 // All implementations must embed UnimplementedDataServiceServer
 // for forward compatibility
 type DataServiceServer interface {
 	Query(context.Context, *QueryRequest) (*QueryResponse, error)
+	// Exec runs one INSERT, UPDATE or DELETE with bound parameters,
+	// data holds inserted_id and rows_affected like Query.
+	Exec(context.Context, *ParamsRequest) (*QueryResponse, error)
+	// QueryParams runs one SELECT with bound parameters,
+	// data holds count and rows like Query.
+	QueryParams(context.Context, *ParamsRequest) (*QueryResponse, error)
+	// Transaction runs statements in order in one database transaction,
+	// they are all committed or all rolled back.
+	Transaction(context.Context, *TransactionRequest) (*TransactionResponse, error)
 	mustEmbedUnimplementedDataServiceServer()
 }
 
@@ -61,6 +109,15 @@ type UnimplementedDataServiceServer struct {
 func (UnimplementedDataServiceServer) Query(context.Context, *QueryRequest) (*QueryResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Query not implemented")
 }
+func (UnimplementedDataServiceServer) Exec(context.Context, *ParamsRequest) (*QueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Exec not implemented")
+}
+func (UnimplementedDataServiceServer) QueryParams(context.Context, *ParamsRequest) (*QueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryParams not implemented")
+}
+func (UnimplementedDataServiceServer) Transaction(context.Context, *TransactionRequest) (*TransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transaction not implemented")
+}
 func (UnimplementedDataServiceServer) mustEmbedUnimplementedDataServiceServer() {}
 
 // UnsafeDataServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -92,6 +149,60 @@ func _DataService_Query_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DataService_Exec_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ParamsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServiceServer).Exec(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataService_Exec_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServiceServer).Exec(ctx, req.(*ParamsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataService_QueryParams_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ParamsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServiceServer).QueryParams(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataService_QueryParams_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServiceServer).QueryParams(ctx, req.(*ParamsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataService_Transaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServiceServer).Transaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataService_Transaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServiceServer).Transaction(ctx, req.(*TransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DataService_ServiceDesc is the grpc.ServiceDesc for DataService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -103,6 +214,18 @@ var DataService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Query",
 			Handler:    _DataService_Query_Handler,
 		},
+		{
+			MethodName: "Exec",
+			Handler:    _DataService_Exec_Handler,
+		},
+		{
+			MethodName: "QueryParams",
+			Handler:    _DataService_QueryParams_Handler,
+		},
+		{
+			MethodName: "Transaction",
+			Handler:    _DataService_Transaction_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/service.proto",
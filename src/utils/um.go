@@ -3,13 +3,19 @@ package utils
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"time"
 )
 
+// UMClient sends every UM request. The timeout bounds a hung request, a transaction that
+// times out is reported with ErrNoAnswer since UM may still apply it.
+var UMClient = &http.Client{Timeout: 10 * time.Second}
+
 // UMRequestData defines the request data structure for user management operations.
 type UMRequestData struct {
 	XKey   string `json:"X_KEY"`
@@ -58,8 +64,7 @@ func VerifyJWT(userToken string) (map[string]interface{}, error) {
 	req.Header.Set("Authorization", "Bearer "+appToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := UMClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -118,8 +123,7 @@ func GetUser(userID int) (map[string]interface{}, error) {
 	req.Header.Set("Authorization", "Bearer "+appToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := UMClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -145,6 +149,10 @@ func GetUser(userID int) (map[string]interface{}, error) {
 
 /* Transaction */
 
+// ErrNoAnswer wraps the failures of a transaction request UM may have received: it was
+// sent but no answer was read, so UM may or may not have applied it.
+var ErrNoAnswer = errors.New("no UM answer")
+
 // UMTransactionData defines the structure of transaction data.
 type UMTransactionData struct {
 	XKey        string  `json:"X_KEY"`
@@ -202,10 +210,9 @@ func AddTransaction(userID int, txType, referenceID string, amount float64, txRe
 	req.Header.Set("Authorization", "Bearer "+appToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := UMClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w: %w", ErrNoAnswer, err)
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -216,12 +223,12 @@ func AddTransaction(userID int, txType, referenceID string, amount float64, txRe
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w: %w", ErrNoAnswer, err)
 	}
 
 	var result map[string]interface{}
 	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("invalid JSON response: %w", err)
+		return nil, fmt.Errorf("invalid JSON response: %w: %w", ErrNoAnswer, err)
 	}
 
 	return result, nil
@@ -283,8 +290,7 @@ func AddXp(userID, amount int, reason, createdBy string) (map[string]interface{}
 	req.Header.Set("Authorization", "Bearer "+appToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := UMClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}